@@ -1,15 +1,17 @@
 package agg
 
 import (
+	"cmp"
 	"context"
 	"database/sql"
-	"encoding/xml"
 	"errors"
+	"flag"
 	"fmt"
 	"html"
-	"io"
+	"log/slog"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -18,58 +20,232 @@ import (
 )
 
 type RSSFeed struct {
-	Channel struct {
-		Title       string    `xml:"title"`
-		Link        string    `xml:"link"`
-		Description string    `xml:"description"`
-		Item        []RSSItem `xml:"item"`
-	} `xml:"channel"`
+	Channel RSSChannel `xml:"channel"`
+}
+
+type RSSChannel struct {
+	Title           string    `xml:"title"`
+	Link            string    `xml:"link"`
+	Description     string    `xml:"description"`
+	Item            []RSSItem `xml:"item"`
+	TTL             string    `xml:"ttl"`
+	SkipHours       []string  `xml:"skipHours>hour"`
+	SkipDays        []string  `xml:"skipDays>day"`
+	UpdatePeriod    string    `xml:"http://purl.org/rss/1.0/modules/syndication/ updatePeriod"`
+	UpdateFrequency string    `xml:"http://purl.org/rss/1.0/modules/syndication/ updateFrequency"`
 }
 
 type RSSItem struct {
-	Title       string `xml:"title"`
-	Link        string `xml:"link"`
-	Description string `xml:"description"`
-	PubDate     string `xml:"pubDate"`
+	Title       string         `xml:"title"`
+	Link        string         `xml:"link"`
+	Description string         `xml:"description"`
+	PubDate     string         `xml:"pubDate"`
+	GUID        string         `xml:"guid"`
+	Enclosures  []RSSEnclosure `xml:"enclosure"`
+	Content     string         `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Author      string         `xml:"author"`
+	Creators    []string       `xml:"http://purl.org/dc/elements/1.1/ creator"`
 }
 
-func AggHandler(state *config.State, command config.Command) error {
+type RSSEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length string `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
 
-	if len(command.Args) < 1 {
+func AggHandler(ctx context.Context, state *config.State, command config.Command) error {
+
+	flags := flag.NewFlagSet("agg", flag.ContinueOnError)
+	workers := flags.Int("workers", 1, "number of feeds fetched concurrently")
+	hostLimit := flags.Int("host-limit", 2, "number of feeds fetched concurrently from the same host")
+	maxInterval := flags.Duration("max-interval", 24*time.Hour, "longest time between two fetches of a feed that rarely publishes")
+	lease := flags.Duration("lease", 10*time.Minute, "how long a feed stays claimed by this process while it is fetched")
+	grace := flags.Duration("grace", 30*time.Second, "how long feeds being fetched may take to finish once agg is asked to stop")
+	once := flags.Bool("once", false, "fetch every due feed once and exit, with an error if any feed failed")
+	fetchLogKeep := flags.Int("fetch-log-keep", 100, "number of fetches kept per feed in the fetch log")
+	fetchLogBodies := flags.Bool("fetch-log-bodies", false, "keep the response body of failed fetches in the fetch log")
+	maxFeedBytes := flags.Int64("max-feed-bytes", cmp.Or(state.Config.FeedMaxBytes, defaultFeedMaxBytes), "largest feed body read once decompressed, 0 for no limit")
+	maxItems := flags.Int("max-items", cmp.Or(state.Config.FeedMaxItems, defaultFeedMaxItems), "most items read from a feed, 0 for no limit")
+	metricsAddr := flags.String("metrics-addr", "", "address to serve prometheus metrics on at /metrics. eg: :9090")
+	scheduleExpr := flags.String("schedule", "", "cron expression of when to fetch due feeds. eg: \"*/15 7-22 * * *\"")
+
+	args, err := command.ParseFlags(flags)
+
+	if err != nil {
+		return err
+	}
+
+	if *once && *scheduleExpr != "" {
+		return errors.New("--once and --schedule cannot be used together")
+	}
+
+	// when runs are triggered by --once or --schedule, the time between
+	// updates only bounds how often a single feed is fetched
+	if len(args) < 1 && !*once && *scheduleExpr == "" {
 		return errors.New("not enough arguments provided. specify time between updates. eg: 1s, 1m, 1h")
 	}
 
-	timeBetweenUpdates := command.Args[0]
-	duration, err := time.ParseDuration(timeBetweenUpdates)
+	if *workers < 1 || *hostLimit < 1 {
+		return errors.New("--workers and --host-limit must be at least 1")
+	}
 
-	
-	if err != nil {
-		return fmt.Errorf("invalid duration: %w", err)
+	if *fetchLogKeep < 1 {
+		return errors.New("--fetch-log-keep must be at least 1")
 	}
-	
-	fmt.Println("Collecting feeds every", duration)
-	
-	ticker := time.NewTicker(duration)
+
+	var duration time.Duration
+
+	if len(args) > 0 {
+		duration, err = time.ParseDuration(args[0])
+
+		if err != nil {
+			return fmt.Errorf("invalid duration: %w", err)
+		}
+	}
+
+	var schedule *cronSchedule
+
+	if *scheduleExpr != "" {
+		schedule, err = parseCron(*scheduleExpr)
+
+		if err != nil {
+			return err
+		}
+
+		if schedule.next(time.Now()).IsZero() {
+			return fmt.Errorf("schedule %q never runs", *scheduleExpr)
+		}
+	}
+
+	if *maxInterval < duration {
+		*maxInterval = duration
+	}
+
+	opts := aggOptions{
+		Interval:    duration,
+		MaxInterval: *maxInterval,
+		WorkerID:    newWorkerID(),
+		Lease:       *lease,
+		Workers:     *workers,
+		Limiter:     newHostLimiter(*hostLimit),
+		Stats:       &aggStats{},
+
+		Limits: feedLimits{
+			MaxBytes: *maxFeedBytes,
+			MaxItems: *maxItems,
+		},
+
+		FetchLogKept:   *fetchLogKeep,
+		FetchLogBodies: *fetchLogBodies,
+	}
+
+	if *metricsAddr != "" {
+		err := serveMetrics(ctx, *metricsAddr)
+
+		if err != nil {
+			return err
+		}
+
+		// time every query agg sends, see txQueries for transactions
+		state.DbQueries = database.New(timedDB{db: state.Db})
+		queriesTimed = true
+	}
+
+	// feeds being fetched when agg is stopped get a grace period to finish
+	// and save their posts before they are cancelled too
+	workCtx, cancelWork := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelWork()
+
+	context.AfterFunc(ctx, func() {
+		time.AfterFunc(*grace, cancelWork)
+	})
+
+	if *once {
+		slog.Info("collecting due feeds", "workers", *workers, "worker_id", opts.WorkerID)
+
+		err := scrapeFeeds(ctx, workCtx, state, opts)
+		opts.Stats.log()
+
+		if err != nil {
+			return fmt.Errorf("failed to fetch feed: %w", err)
+		}
+
+		if failed := opts.Stats.Failed.Load(); failed > 0 {
+			return fmt.Errorf("failed to fetch %d feeds", failed)
+		}
+
+		return nil
+	}
+
+	// feeds fall due at their own pace, so look for due feeds more often
+	// than the interval when it is long
+	ticker := time.NewTicker(min(max(duration, time.Second), time.Minute))
 
 	defer ticker.Stop()
 
-	for ;; <-ticker.C {
-		err := scrapeFeeds(state)
+	nextRun := func() <-chan time.Time {
+		return ticker.C
+	}
+
+	if schedule != nil {
+		slog.Info("collecting feeds on schedule", "schedule", *scheduleExpr, "workers", *workers, "worker_id", opts.WorkerID)
+
+		nextRun = func() <-chan time.Time {
+			next := schedule.next(time.Now())
+			slog.Debug("waiting for next collection", "at", next)
+			return time.After(time.Until(next))
+		}
+	} else {
+		slog.Info("collecting feeds", "every", duration, "workers", *workers, "worker_id", opts.WorkerID)
+
+		err := scrapeFeeds(ctx, workCtx, state, opts)
+		if err != nil {
+			return fmt.Errorf("failed to fetch feed: %w", err)
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("stopped collecting feeds")
+			opts.Stats.log()
+			return nil
+		case <-nextRun():
+		}
+
+		err := scrapeFeeds(ctx, workCtx, state, opts)
 		if err != nil {
 			return fmt.Errorf("failed to fetch feed: %w", err)
 		}
 	}
 }
 
-func AddFeedHandler(state *config.State, command config.Command, user database.User) error {
-	if len(command.Args) < 2 {
+func AddFeedHandler(ctx context.Context, state *config.State, command config.Command, user database.User) error {
+	flags := flag.NewFlagSet("addfeed", flag.ContinueOnError)
+	noDiscover := flags.Bool("no-discover", false, "add the url as is, without fetching it to look for a feed")
+
+	args, err := command.ParseFlags(flags)
+
+	if err != nil {
+		return err
+	}
+
+	if len(args) < 2 {
 		return fmt.Errorf("not enough arguments provided. name and url arguments are required")
 	}
 
-	feedName := command.Args[0]
-	feedUrl := command.Args[1]
+	feedName := args[0]
+	feedUrl := args[1]
+
+	if !*noDiscover {
+		feedUrl, err = resolveFeedURL(ctx, state.HTTPClient, args[1])
+
+		if err != nil {
+			return fmt.Errorf("failed to find a feed at %s: %w", args[1], err)
+		}
+	}
 
-	feedEntry, err := state.DbQueries.CreateFeed(context.Background(), database.CreateFeedParams{
+	feedEntry, err := state.DbQueries.CreateFeed(ctx, database.CreateFeedParams{
 		ID: uuid.New(),
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
@@ -82,7 +258,7 @@ func AddFeedHandler(state *config.State, command config.Command, user database.U
 		return fmt.Errorf("failed to create feed entry: %w", err)
 	}
 
-	_, err = state.DbQueries.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{
+	_, err = state.DbQueries.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
 		ID: uuid.New(),
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
@@ -105,60 +281,33 @@ func AddFeedHandler(state *config.State, command config.Command, user database.U
 	return nil
 }
 
-func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
-	request, err := http.NewRequestWithContext(ctx, "GET", feedURL, nil)
-
-	request.Header.Set("User-Agent", "gator")
-
-	if err != nil {
-		return nil, err
-	}
-
-	httpClient := &http.Client{}
-
-	response, err := httpClient.Do(request)
-
-	if err != nil {
-		return nil, err
-	}
-
-	if response.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("status code: %d", response.StatusCode)
-	}
-
-	defer response.Body.Close()
-
-	body, err := io.ReadAll(response.Body)
-
-	if err != nil {
-		return nil, err
-	}
-
-	var feed RSSFeed
-
-	err = xml.Unmarshal(body, &feed)
-
-	if err != nil {
-		return nil, err
-	}
-
-	cleanUpRSS(&feed)
-
-	return &feed, nil
-}
-
 func cleanUpRSS(feed *RSSFeed) {
 	feed.Channel.Title = html.UnescapeString(feed.Channel.Title)
 	feed.Channel.Description = html.UnescapeString(feed.Channel.Description)
 
+	// content is left alone, it is markup that is only ever read as markup
 	for i, rssItem := range feed.Channel.Item {
 		feed.Channel.Item[i].Title = html.UnescapeString(rssItem.Title)
 		feed.Channel.Item[i].Description = html.UnescapeString(rssItem.Description)
+		feed.Channel.Item[i].Content = strings.TrimSpace(rssItem.Content)
 	}
 }
 
-func FeedsHandler(state *config.State, command config.Command) error {
-	feedsAndUsers, err := state.DbQueries.FeedsAndUsers(context.Background())
+func FeedsHandler(ctx context.Context, state *config.State, command config.Command) error {
+	flags := flag.NewFlagSet("feeds", flag.ContinueOnError)
+	health := flags.Bool("health", false, "list failing feeds with their recent errors")
+
+	_, err := command.ParseFlags(flags)
+
+	if err != nil {
+		return err
+	}
+
+	if *health {
+		return printFeedHealth(ctx, state)
+	}
+
+	feedsAndUsers, err := state.DbQueries.FeedsAndUsers(ctx)
 
 	if err != nil {
 		return nil
@@ -168,22 +317,33 @@ func FeedsHandler(state *config.State, command config.Command) error {
 		fmt.Printf("Feed: %v\n", feed.Name)
 		fmt.Printf("url: %v\n", feed.Url)
 		fmt.Printf("user: %v\n", feed.Name_2)
+		if feed.PollIntervalSeconds > 0 {
+			fmt.Printf("polled every: %v (from posting frequency)\n", time.Duration(feed.PollIntervalSeconds)*time.Second)
+		}
+		if feed.TtlSeconds > feed.PollIntervalSeconds {
+			fmt.Printf("publisher asks for at most one fetch every: %v\n", time.Duration(feed.TtlSeconds)*time.Second)
+		}
+		if feed.NextFetchAt.Valid {
+			fmt.Printf("next fetch: %s\n", feed.NextFetchAt.Time.Format(time.RFC1123))
+		} else {
+			fmt.Println("next fetch: on the next agg run")
+		}
 	}
 
 	return nil
 }
 
-func FollowHandler(state *config.State, command config.Command, user database.User) error {
+func FollowHandler(ctx context.Context, state *config.State, command config.Command, user database.User) error {
 	feedUrl := command.Args[0]
 
-	feed, err := state.DbQueries.FeedFromUrl(context.Background(), feedUrl)
+	feed, err := state.DbQueries.FeedFromUrl(ctx, feedUrl)
 
 	if err != nil {
 		return err
 	}
 
 	feedFollow, err := state.DbQueries.CreateFeedFollow(
-		context.Background(),
+		ctx,
 		database.CreateFeedFollowParams{
 			ID: uuid.New(),
 			CreatedAt: time.Now(),
@@ -203,8 +363,8 @@ func FollowHandler(state *config.State, command config.Command, user database.Us
 	return nil
 }
 
-func FollowingHandler(state *config.State, command config.Command, user database.User) error {
-	followed_feeds, err := state.DbQueries.GetFeedFollowsForUser(context.Background(), user.ID)
+func FollowingHandler(ctx context.Context, state *config.State, command config.Command, user database.User) error {
+	followed_feeds, err := state.DbQueries.GetFeedFollowsForUser(ctx, user.ID)
 
 	if err != nil {
 		return nil
@@ -217,18 +377,18 @@ func FollowingHandler(state *config.State, command config.Command, user database
 	return nil
 }
 
-func UnfollowHandler(state *config.State, command config.Command, user database.User) error {
+func UnfollowHandler(ctx context.Context, state *config.State, command config.Command, user database.User) error {
 	if len(command.Args) < 1 {
 		return fmt.Errorf("please specify rss url to unfollow")
 	}
 
-	feed, err := state.DbQueries.FeedFromUrl(context.Background(), command.Args[0])
+	feed, err := state.DbQueries.FeedFromUrl(ctx, command.Args[0])
 
 	if err != nil {
 		return err
 	}
 
-	err = state.DbQueries.DeleteFeedFollow(context.Background(), database.DeleteFeedFollowParams{
+	err = state.DbQueries.DeleteFeedFollow(ctx, database.DeleteFeedFollowParams{
 		UserID: user.ID,
 		FeedID: feed.ID,
 	})
@@ -240,20 +400,73 @@ func UnfollowHandler(state *config.State, command config.Command, user database.
 	return nil
 }
 
-func scrapeFeeds(state *config.State) error {
-	feed, err := state.DbQueries.GetNextFeedToFetch(context.Background())	
+// feedLogger logs with the attributes that identify a feed.
+func feedLogger(feed database.Feed) *slog.Logger {
+	return slog.With("feed_id", feed.ID, "url", feed.Url)
+}
+
+func scrapeFeed(ctx context.Context, state *config.State, feed database.Feed, opts aggOptions, attempt *fetchAttempt) error {
+	var result *fetchResult
+
+	auth, err := loadFeedAuth(ctx, state, feed.ID)
+
+	if err == nil {
+		result, err = fetchFeed(ctx, state.HTTPClient, feed.Url, validatorsFromFeed(feed), auth, opts.Limits)
+	} else {
+		err = fmt.Errorf("failed to load credentials: %w", err)
+	}
+
+	if result != nil {
+		attempt.StatusCode = result.StatusCode
+		attempt.Bytes = result.Bytes
+		attempt.Body = result.Body
+	}
+
+	var statusErr *statusError
+
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusGone {
+		disableErr := disableGoneFeed(ctx, state, feed)
+
+		if disableErr != nil {
+			return errors.Join(err, disableErr)
+		}
+
+		// a disabled feed is not claimed again
+		attempt.Rescheduled = true
+
+		return fmt.Errorf("feed is gone and was disabled: %w", err)
+	}
 
 	if err != nil {
+		recordErr := recordFeedFailure(ctx, state, feed, err)
+
+		if recordErr != nil {
+			return errors.Join(err, recordErr)
+		}
+
+		attempt.Rescheduled = true
+
 		return err
 	}
 
-	feedContent, err := fetchFeed(context.Background(), feed.Url)
+	if newURL := result.permanentURL(); newURL != "" && newURL != feed.Url {
+		moved, err := moveFeed(ctx, state, feed, newURL)
 
-	if err != nil {
-		return nil
+		if err != nil {
+			return fmt.Errorf("failed to move feed to %s: %w", newURL, err)
+		}
+
+		// the feed was merged into the one already at newURL. that feed is
+		// not leased by this worker, so it is left to be claimed on its own
+		if moved.ID != feed.ID {
+			attempt.FeedID = moved.ID
+			return nil
+		}
+
+		feed = moved
 	}
 
-	err = state.DbQueries.MarkFeedFetched(context.Background(), database.MarkFeedFetchedParams{
+	err = state.DbQueries.MarkFeedFetched(ctx, database.MarkFeedFetchedParams{
 		ID: feed.ID,
 		LastFetchedAt: sql.NullTime{Time: time.Now(), Valid: true},
 	})
@@ -262,49 +475,278 @@ func scrapeFeeds(state *config.State) error {
 		return err
 	}
 
-	fmt.Printf("Feed Update For %s\n", feedContent.Channel.Title)
-	fmt.Printf("Url: %s\n", feedContent.Channel.Link)
+	hints := hintsFromFeed(feed)
+	if !result.NotModified {
+		hints = hintsFromRSS(result.Feed)
+	}
+
+	publishTimes, err := state.DbQueries.GetRecentPublishTimes(ctx, database.GetRecentPublishTimesParams{
+		FeedID: feed.ID,
+		Limit:  cadenceSamples,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	interval := pollInterval(publishTimes, time.Now(), opts.Interval, opts.MaxInterval)
+
+	err = state.DbQueries.UpdateFeedSchedule(ctx, database.UpdateFeedScheduleParams{
+		ID:                  feed.ID,
+		NextFetchAt:         sql.NullTime{Time: nextFetchAt(time.Now(), interval, hints, result.CacheFor), Valid: true},
+		TtlSeconds:          int32(hints.TTL / time.Second),
+		SkipHours:           hints.SkipHours,
+		SkipDays:            hints.SkipDays,
+		PollIntervalSeconds: int32(interval / time.Second),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	attempt.Rescheduled = true
+
+	opts.Stats.Fetched.Add(1)
+
+	if result.NotModified {
+		opts.Stats.NotModified.Add(1)
+		feedLogger(feed).DebugContext(ctx, "feed not modified", "next_fetch_in", interval)
+		return nil
+	}
+
+	feedContent := result.Feed
+	logger := feedLogger(feed)
+
+	attempt.ItemsDropped = result.DroppedItems
+
+	if result.DroppedItems > 0 {
+		logger.WarnContext(ctx, "feed has more items than the limit, skipped the rest", "max_items", opts.Limits.MaxItems, "dropped_items", result.DroppedItems)
+	}
+
+	failed := 0
+	attempt.ItemsSeen = len(feedContent.Channel.Item)
 
-	for i, item := range(feedContent.Channel.Item) {
+	for _, item := range feedContent.Channel.Item {
+		now := time.Now()
+
+		post, err := savePost(ctx, state, database.UpsertPostParams{
+			ID:          uuid.New(),
+			CreatedAt:   now,
+			UpdatedAt:   now,
+			Title:       item.Title,
+			Url:         item.Link,
+			Description: item.Description,
+			PublishedAt: parsePublishDate(item.PubDate, now),
+			FeedID:      feed.ID,
+			Guid:        itemGUID(item),
+			Content:     item.Content,
+			Author:      itemAuthors(item),
+		}, item.Enclosures)
+
+		// no row comes back when the item exists and its content is unchanged
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
 
-		publishedAt, err := time.Parse(time.RFC1123Z, item.PubDate)
 		if err != nil {
-			return fmt.Errorf("failed to parse publish date: %w", err)
+			logger.WarnContext(ctx, "failed to save item", "item_url", item.Link, "error", err)
+			failed++
+			continue
 		}
 
-		state.DbQueries.CreatePost(context.Background(), database.CreatePostParams{
-			ID: uuid.New(),
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
-			Title: item.Title,
-			Url: item.Link,
-			Description: item.Description,
-			PublishedAt: publishedAt,
-			FeedID: feed.ID,
-		})
+		if post.RevisedAt.Valid {
+			opts.Stats.UpdatedPosts.Add(1)
+			logger.DebugContext(ctx, "updated post", "post_id", post.ID, "title", post.Title, "item_url", post.Url)
+		} else {
+			opts.Stats.NewPosts.Add(1)
+			attempt.ItemsNew++
+			logger.DebugContext(ctx, "saved post", "post_id", post.ID, "title", post.Title, "item_url", post.Url, "published_at", post.PublishedAt)
+		}
+	}
 
+	logger.InfoContext(ctx, "fetched feed",
+		"title", feedContent.Channel.Title,
+		"items_seen", attempt.ItemsSeen,
+		"items_new", attempt.ItemsNew,
+		"items_failed", failed,
+	)
 
-		fmt.Printf("Item #%v:\n", i)
-		fmt.Println(item.Title)
-		fmt.Println(item.PubDate)
-		fmt.Println(item.Link)
-		fmt.Println(item.Description)
+	if failed > 0 {
+		return fmt.Errorf("failed to save %d of %d items", failed, len(feedContent.Channel.Item))
+	}
+
+	// only remember the validators once every item made it in, otherwise a
+	// failed run would make the next one skip the same content as unchanged
+	err = state.DbQueries.UpdateFeedCacheValidators(ctx, database.UpdateFeedCacheValidatorsParams{
+		ID:           feed.ID,
+		Etag:         result.Validators.ETag,
+		LastModified: result.Validators.LastModified,
+		ContentHash:  result.Validators.ContentHash,
+	})
+
+	if err != nil {
+		return err
 	}
 
 	return nil
 }
 
 
-func BrowseHandler(state *config.State, command config.Command, user database.User) error {
+// savePost upserts a post with its enclosures and keeps its previous title
+// and description as a revision when either changed. It returns
+// sql.ErrNoRows when the post is unchanged.
+func savePost(ctx context.Context, state *config.State, params database.UpsertPostParams, enclosures []RSSEnclosure) (database.Post, error) {
+	tx, err := state.Db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return database.Post{}, err
+	}
+
+	defer tx.Rollback()
+
+	queries := txQueries(state, tx)
+
+	// posts saved before guids were stored use their url as guid
+	err = queries.AdoptLegacyPost(ctx, database.AdoptLegacyPostParams{
+		Guid:   params.Guid,
+		FeedID: params.FeedID,
+		Url:    params.Url,
+	})
+
+	if err != nil {
+		return database.Post{}, err
+	}
+
+	revisions, err := queries.CreatePostRevision(ctx, database.CreatePostRevisionParams{
+		ID:          uuid.New(),
+		CreatedAt:   params.UpdatedAt,
+		FeedID:      params.FeedID,
+		Guid:        params.Guid,
+		Title:       params.Title,
+		Description: params.Description,
+		Content:     params.Content,
+	})
+
+	if err != nil {
+		return database.Post{}, err
+	}
+
+	post, err := queries.UpsertPost(ctx, params)
+	unchanged := errors.Is(err, sql.ErrNoRows)
+
+	// an existing post whose content was only filled in is not revised
+	if err == nil && post.ID != params.ID && revisions == 0 {
+		unchanged = true
+	}
+
+	// an unchanged post may still have new enclosures
+	if errors.Is(err, sql.ErrNoRows) {
+		post, err = queries.GetPostByGuid(ctx, database.GetPostByGuidParams{
+			FeedID: params.FeedID,
+			Guid:   params.Guid,
+		})
+	}
+
+	if err != nil {
+		return database.Post{}, err
+	}
+
+	for _, enclosure := range enclosures {
+		if strings.TrimSpace(enclosure.URL) == "" {
+			continue
+		}
+
+		// the length is advisory and often missing or set to 0
+		length, _ := strconv.ParseInt(strings.TrimSpace(enclosure.Length), 10, 64)
+
+		err = queries.UpsertEnclosure(ctx, database.UpsertEnclosureParams{
+			ID:        uuid.New(),
+			CreatedAt: params.UpdatedAt,
+			UpdatedAt: params.UpdatedAt,
+			PostID:    post.ID,
+			Url:       strings.TrimSpace(enclosure.URL),
+			Length:    max(length, 0),
+			MimeType:  strings.TrimSpace(enclosure.Type),
+		})
+
+		if err != nil {
+			return database.Post{}, err
+		}
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		return database.Post{}, err
+	}
+
+	if unchanged {
+		return database.Post{}, sql.ErrNoRows
+	}
+
+	return post, nil
+}
+
+// itemAuthors joins the names of the item authors. The RSS author element is
+// an email address optionally followed by the name in parentheses.
+func itemAuthors(item RSSItem) string {
+	var names []string
+
+	for _, creator := range item.Creators {
+		if creator = strings.TrimSpace(html.UnescapeString(creator)); creator != "" {
+			names = append(names, creator)
+		}
+	}
+
+	if len(names) == 0 {
+		author := strings.TrimSpace(html.UnescapeString(item.Author))
+
+		open := strings.Index(author, "(")
+		if open >= 0 && strings.HasSuffix(author, ")") {
+			author = strings.TrimSpace(author[open+1 : len(author)-1])
+		}
+
+		if author != "" {
+			names = append(names, author)
+		}
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// itemGUID identifies an item within its feed. Feeds without guids fall back
+// to the link, and to the title when there is not even a link.
+func itemGUID(item RSSItem) string {
+	if guid := strings.TrimSpace(item.GUID); guid != "" {
+		return guid
+	}
+
+	if link := strings.TrimSpace(item.Link); link != "" {
+		return link
+	}
+
+	return item.Title
+}
+
+func BrowseHandler(ctx context.Context, state *config.State, command config.Command, user database.User) error {
+	flags := flag.NewFlagSet("browse", flag.ContinueOnError)
+	full := flags.Bool("full", false, "show the full content of posts instead of their summary")
+
+	args, err := command.ParseFlags(flags)
+
+	if err != nil {
+		return err
+	}
+
 	limit := 2
-	if len(command.Args) >= 1 {
-		parsedLimit, err := strconv.Atoi(command.Args[0])
+	if len(args) >= 1 {
+		parsedLimit, err := strconv.Atoi(args[0])
 		if err == nil {
 			limit = parsedLimit
 		}
 	}
 
-	items, err := state.DbQueries.GetPostsByUser(context.Background(), database.GetPostsByUserParams{
+	items, err := state.DbQueries.GetPostsByUser(ctx, database.GetPostsByUserParams{
 		UserID: user.ID,
 		Limit: int32(limit),
 	})
@@ -318,8 +760,34 @@ func BrowseHandler(state *config.State, command config.Command, user database.Us
 		fmt.Printf("Item #%v:\n", i)
 		fmt.Println(item.Title)
 		fmt.Println(item.PublishedAt)
+		if item.RevisedAt.Valid {
+			fmt.Printf("Updated: %v\n", item.RevisedAt.Time)
+		}
+		if item.Author != "" {
+			fmt.Printf("By: %s\n", item.Author)
+		}
 		fmt.Println(item.Url)
-		fmt.Println(item.Description)
+
+		// feeds that only publish full content have no separate summary
+		if (*full && item.Content != "") || item.Description == "" {
+			fmt.Println(item.Content)
+		} else {
+			fmt.Println(item.Description)
+		}
+
+		enclosures, err := state.DbQueries.GetEnclosuresForPost(ctx, item.ID)
+
+		if err != nil {
+			return err
+		}
+
+		for _, enclosure := range enclosures {
+			if enclosure.Length > 0 {
+				fmt.Printf("Enclosure: %s (%s, %s)\n", enclosure.Url, enclosure.MimeType, formatBytes(enclosure.Length))
+			} else {
+				fmt.Printf("Enclosure: %s (%s)\n", enclosure.Url, enclosure.MimeType)
+			}
+		}
 	}
 
 	return nil
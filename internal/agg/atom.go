@@ -0,0 +1,156 @@
+package agg
+
+import (
+	"net/url"
+	"strings"
+)
+
+type AtomFeed struct {
+	Title    AtomText     `xml:"title"`
+	Authors  []AtomPerson `xml:"author"`
+	Subtitle AtomText     `xml:"subtitle"`
+	Links    []AtomLink   `xml:"link"`
+	Entries  []AtomEntry  `xml:"entry"`
+}
+
+type AtomEntry struct {
+	ID        string       `xml:"id"`
+	Title     AtomText     `xml:"title"`
+	Links     []AtomLink   `xml:"link"`
+	Summary   AtomText     `xml:"summary"`
+	Content   AtomText     `xml:"content"`
+	Updated   string       `xml:"updated"`
+	Published string       `xml:"published"`
+	Authors   []AtomPerson `xml:"author"`
+}
+
+type AtomPerson struct {
+	Name  string `xml:"name"`
+	Email string `xml:"email"`
+}
+
+type AtomLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
+}
+
+// AtomText is an atom text construct. xhtml content is kept as markup since
+// its chardata alone would drop every tag inside the wrapping div.
+type AtomText struct {
+	Type     string `xml:"type,attr"`
+	Text     string `xml:",chardata"`
+	InnerXML string `xml:",innerxml"`
+}
+
+func (t AtomText) String() string {
+	if t.Type == "xhtml" {
+		return strings.TrimSpace(t.InnerXML)
+	}
+
+	return strings.TrimSpace(t.Text)
+}
+
+func atomToRSS(atom *AtomFeed, feedURL string) *RSSFeed {
+	var feed RSSFeed
+
+	feed.Channel.Title = atom.Title.String()
+	feed.Channel.Description = atom.Subtitle.String()
+	feed.Channel.Link = resolveLink(feedURL, atomAlternateLink(atom.Links))
+
+	for _, entry := range atom.Entries {
+		description := entry.Summary.String()
+		if description == "" {
+			description = entry.Content.String()
+		}
+
+		// entries without authors inherit the feed authors
+		authors := entry.Authors
+		if len(authors) == 0 {
+			authors = atom.Authors
+		}
+
+		var creators []string
+
+		for _, author := range authors {
+			if author.Name != "" {
+				creators = append(creators, author.Name)
+			} else if author.Email != "" {
+				creators = append(creators, author.Email)
+			}
+		}
+
+		pubDate := entry.Published
+		if pubDate == "" {
+			pubDate = entry.Updated
+		}
+
+		var enclosures []RSSEnclosure
+
+		for _, link := range entry.Links {
+			if link.Rel == "enclosure" {
+				enclosures = append(enclosures, RSSEnclosure{
+					URL:    resolveLink(feedURL, strings.TrimSpace(link.Href)),
+					Length: link.Length,
+					Type:   link.Type,
+				})
+			}
+		}
+
+		feed.Channel.Item = append(feed.Channel.Item, RSSItem{
+			Title:       entry.Title.String(),
+			Link:        resolveLink(feedURL, atomAlternateLink(entry.Links)),
+			Description: description,
+			PubDate:     strings.TrimSpace(pubDate),
+			GUID:        strings.TrimSpace(entry.ID),
+			Enclosures:  enclosures,
+			Content:     entry.Content.String(),
+			Creators:    creators,
+		})
+	}
+
+	return &feed
+}
+
+// atomAlternateLink picks the link pointing at the html version of the
+// entry. A link without a rel attribute is an alternate link per RFC 4287.
+func atomAlternateLink(links []AtomLink) string {
+	fallback := ""
+
+	for _, link := range links {
+		if link.Rel != "" && link.Rel != "alternate" {
+			continue
+		}
+
+		if link.Type == "" || strings.Contains(link.Type, "html") {
+			return strings.TrimSpace(link.Href)
+		}
+
+		if fallback == "" {
+			fallback = strings.TrimSpace(link.Href)
+		}
+	}
+
+	return fallback
+}
+
+func resolveLink(base string, link string) string {
+	if link == "" {
+		return ""
+	}
+
+	baseURL, err := url.Parse(base)
+
+	if err != nil {
+		return link
+	}
+
+	linkURL, err := url.Parse(link)
+
+	if err != nil {
+		return link
+	}
+
+	return baseURL.ResolveReference(linkURL).String()
+}
@@ -0,0 +1,144 @@
+package agg
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseAtomFeed(t *testing.T) {
+	tests := []struct {
+		file    string
+		feedURL string
+		title   string
+		link    string
+		items   []RSSItem
+	}{
+		{
+			file:    "github_releases.atom",
+			feedURL: "https://github.com/golang/go/releases.atom",
+			title:   "Release notes from go",
+			link:    "https://github.com/golang/go/releases",
+			items: []RSSItem{
+				{
+					Title:       "go1.23.2",
+					Link:        "https://github.com/golang/go/releases/tag/go1.23.2",
+					Description: "<p>Fixes a crash in the <code>net/http</code> client.</p>",
+					PubDate:     "2026-10-01T17:04:12Z",
+					GUID:        "tag:github.com,2008:Repository/23096959/go1.23.2",
+					Content:     "<p>Fixes a crash in the <code>net/http</code> client.</p>",
+					Creators:    []string{"gopherbot"},
+				},
+				{
+					Title:       "go1.23.1",
+					Link:        "https://github.com/golang/go/releases/tag/go1.23.1",
+					Description: "No content.",
+					PubDate:     "2026-09-05T16:20:00Z",
+					GUID:        "tag:github.com,2008:Repository/23096959/go1.23.1",
+					Content:     "No content.",
+					Creators:    []string{"gopherbot"},
+				},
+			},
+		},
+		{
+			file:    "xhtml_content.atom",
+			feedURL: "https://example.com/feed.atom",
+			title:   `<div xmlns="http://www.w3.org/1999/xhtml">A <em>lot</em> of words</div>`,
+			link:    "https://example.com/",
+			items: []RSSItem{
+				{
+					Title:       "Markup in content",
+					Link:        "https://example.com/markup",
+					Description: "A post with markup",
+					PubDate:     "2026-10-03T09:30:00+02:00",
+					GUID:        "urn:uuid:1225c695-cfb8-4ebb-aaaa-80da344efa6a",
+					Content:     `<div xmlns="http://www.w3.org/1999/xhtml"><p>Hello <b>world</b> &amp; friends</p></div>`,
+					Creators:    []string{"Jane Doe"},
+				},
+			},
+		},
+		{
+			file:    "relative_links.atom",
+			feedURL: "https://example.com/blog/feed.atom",
+			title:   "Relative links",
+			link:    "https://example.com/blog/",
+			items: []RSSItem{
+				{
+					Title:   "Absolute path",
+					Link:    "https://example.com/blog/first",
+					PubDate: "2026-10-05T08:00:00Z",
+					GUID:    "https://example.com/blog/first",
+					Enclosures: []RSSEnclosure{
+						{URL: "https://example.com/blog/media/first.mp3", Length: "1234", Type: "audio/mpeg"},
+					},
+				},
+				{
+					Title:   "Relative path",
+					Link:    "https://example.com/blog/second.html",
+					PubDate: "2026-10-06T08:00:00Z",
+					GUID:    "https://example.com/blog/second",
+				},
+			},
+		},
+		{
+			file:    "no_alternate_link.atom",
+			feedURL: "https://example.com/feed.atom",
+			title:   "Missing links",
+			items: []RSSItem{
+				{
+					Title:       "Only a self link",
+					Description: "Content without a summary",
+					PubDate:     "2026-10-07T08:00:00Z",
+					GUID:        "tag:example.com,2026:self-only",
+					Content:     "Content without a summary",
+				},
+				{
+					Title:   "Only a pdf",
+					Link:    "https://example.com/paper.pdf",
+					PubDate: "2026-10-08T08:00:00Z",
+					GUID:    "tag:example.com,2026:pdf",
+				},
+				{
+					Title:   "No link at all",
+					PubDate: "2026-10-09T08:00:00Z",
+					GUID:    "tag:example.com,2026:none",
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.file, func(t *testing.T) {
+			body, err := os.ReadFile(filepath.Join("testdata", test.file))
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			feed, err := parseFeed(body, "application/atom+xml", test.feedURL)
+
+			if err != nil {
+				t.Fatalf("parseFeed() error = %v", err)
+			}
+
+			if feed.Channel.Title != test.title {
+				t.Errorf("title = %q, want %q", feed.Channel.Title, test.title)
+			}
+
+			if feed.Channel.Link != test.link {
+				t.Errorf("link = %q, want %q", feed.Channel.Link, test.link)
+			}
+
+			if len(feed.Channel.Item) != len(test.items) {
+				t.Fatalf("got %d items, want %d", len(feed.Channel.Item), len(test.items))
+			}
+
+			for i, item := range feed.Channel.Item {
+				if !reflect.DeepEqual(item, test.items[i]) {
+					t.Errorf("item %d = %+v, want %+v", i, item, test.items[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,337 @@
+package agg
+
+import (
+	"bufio"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/samuelea/gator/internal/config"
+	"github.com/samuelea/gator/internal/database"
+)
+
+// feedAuth is what is sent along with every request for a private feed. It
+// is stored encrypted, see sealFeedAuth.
+type feedAuth struct {
+	Username    string            `json:"username,omitempty"`
+	Password    string            `json:"password,omitempty"`
+	BearerToken string            `json:"bearer_token,omitempty"`
+	Cookies     []string          `json:"cookies,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+}
+
+func (a *feedAuth) apply(request *http.Request) {
+	if a == nil {
+		return
+	}
+
+	for name, value := range a.Headers {
+		request.Header.Set(name, value)
+	}
+
+	if len(a.Cookies) > 0 {
+		request.Header.Set("Cookie", strings.Join(a.Cookies, "; "))
+	}
+
+	if a.Username != "" {
+		request.SetBasicAuth(a.Username, a.Password)
+	}
+
+	if a.BearerToken != "" {
+		request.Header.Set("Authorization", "Bearer "+a.BearerToken)
+	}
+}
+
+func (a *feedAuth) isEmpty() bool {
+	return a.Username == "" && a.BearerToken == "" && len(a.Cookies) == 0 && len(a.Headers) == 0
+}
+
+// sealFeedAuth encrypts credentials with AES-GCM. The feed id is bound to
+// the ciphertext so credentials cannot be moved to another feed in the
+// database.
+func sealFeedAuth(key []byte, feedID uuid.UUID, auth *feedAuth) ([]byte, error) {
+	plaintext, err := json.Marshal(auth)
+
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+
+	_, err = rand.Read(nonce)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return gcm.Seal(nonce, nonce, plaintext, feedID[:]), nil
+}
+
+func openFeedAuth(key []byte, feedID uuid.UUID, sealed []byte) (*feedAuth, error) {
+	gcm, err := newGCM(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("stored credentials are truncated")
+	}
+
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, feedID[:])
+
+	if err != nil {
+		return nil, errors.New("failed to decrypt credentials, was the credentials key changed?")
+	}
+
+	var auth feedAuth
+
+	err = json.Unmarshal(plaintext, &auth)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &auth, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// loadFeedAuth returns the credentials of a feed, or nil for public feeds.
+func loadFeedAuth(ctx context.Context, state *config.State, feedID uuid.UUID) (*feedAuth, error) {
+	stored, err := state.DbQueries.GetFeedAuth(ctx, feedID)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := state.Config.FeedCredentialsKey()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return openFeedAuth(key, feedID, stored.Credentials)
+}
+
+func FeedHandler(ctx context.Context, state *config.State, command config.Command, user database.User) error {
+	if len(command.Args) < 1 {
+		return errors.New("please specify a subcommand. eg: feed auth <url> or feed keep <url> <n>")
+	}
+
+	switch command.Args[0] {
+	case "auth":
+		return feedAuthHandler(ctx, state, config.Command{Name: "feed auth", Args: command.Args[1:]}, user)
+	case "keep":
+		return feedKeepHandler(ctx, state, config.Command{Name: "feed keep", Args: command.Args[1:]}, user)
+	default:
+		return fmt.Errorf("unknown subcommand feed %s", command.Args[0])
+	}
+}
+
+func feedAuthHandler(ctx context.Context, state *config.State, command config.Command, user database.User) error {
+	flags := flag.NewFlagSet("feed auth", flag.ContinueOnError)
+	username := flags.String("user", "", "user name for HTTP Basic auth. the password is read from stdin unless --password is given")
+	password := flags.String("password", "", "password for HTTP Basic auth")
+	bearer := flags.String("bearer", "", "bearer token sent in the Authorization header")
+	clearAuth := flags.Bool("clear", false, "remove every credential and header of the feed before applying the others")
+
+	var cookies []string
+	flags.Func("cookie", "cookie sent with every request, eg: session=abc. may be repeated", func(value string) error {
+		cookies = append(cookies, value)
+		return nil
+	})
+
+	headers := map[string]string{}
+	flags.Func("header", "header sent with every request, eg: \"X-Api-Key: abc\". may be repeated", func(value string) error {
+		name, headerValue, ok := strings.Cut(value, ":")
+		name = strings.TrimSpace(name)
+
+		if !ok || name == "" || strings.ContainsAny(name, " \t") {
+			return fmt.Errorf("invalid header %q. use \"Name: value\"", value)
+		}
+
+		headers[http.CanonicalHeaderKey(name)] = strings.TrimSpace(headerValue)
+		return nil
+	})
+
+	args, err := command.ParseFlags(flags)
+
+	if err != nil {
+		return err
+	}
+
+	if len(args) < 1 {
+		return errors.New("please specify the url of the feed")
+	}
+
+	if *password != "" && *username == "" {
+		return errors.New("--password needs --user")
+	}
+
+	feed, err := state.DbQueries.FeedFromUrl(ctx, args[0])
+
+	if err != nil {
+		return fmt.Errorf("failed to find feed %s: %w", args[0], err)
+	}
+
+	if feed.UserID != user.ID {
+		return fmt.Errorf("only the user who added %s can set its credentials", feed.Url)
+	}
+
+	auth, err := loadFeedAuth(ctx, state, feed.ID)
+
+	if err != nil {
+		return err
+	}
+
+	if auth == nil || *clearAuth {
+		auth = &feedAuth{}
+	}
+
+	changed := *clearAuth
+
+	flags.Visit(func(f *flag.Flag) {
+		changed = true
+	})
+
+	if !changed {
+		printFeedAuth(feed, auth)
+		return nil
+	}
+
+	if *username != "" {
+		auth.Username = *username
+		auth.Password = *password
+
+		if auth.Password == "" {
+			auth.Password, err = readPassword(os.Stdin)
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if *bearer != "" {
+		auth.BearerToken = *bearer
+	}
+
+	auth.Cookies = append(auth.Cookies, cookies...)
+
+	for name, value := range headers {
+		if auth.Headers == nil {
+			auth.Headers = map[string]string{}
+		}
+
+		auth.Headers[name] = value
+	}
+
+	if auth.isEmpty() {
+		err = state.DbQueries.DeleteFeedAuth(ctx, feed.ID)
+
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Removed the credentials of %s\n", feed.Url)
+		return nil
+	}
+
+	key, err := state.Config.FeedCredentialsKey()
+
+	if err != nil {
+		return err
+	}
+
+	sealed, err := sealFeedAuth(key, feed.ID, auth)
+
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	err = state.DbQueries.UpsertFeedAuth(ctx, database.UpsertFeedAuthParams{
+		FeedID:      feed.ID,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Credentials: sealed,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	printFeedAuth(feed, auth)
+
+	return nil
+}
+
+func readPassword(input io.Reader) (string, error) {
+	fmt.Print("Password: ")
+
+	line, err := bufio.NewReader(input).ReadString('\n')
+
+	if err != nil && line == "" {
+		return "", fmt.Errorf("no password entered: %w", err)
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// printFeedAuth shows which credentials a feed uses without their secrets.
+func printFeedAuth(feed database.Feed, auth *feedAuth) {
+	if auth.isEmpty() {
+		fmt.Printf("%s is fetched without credentials\n", feed.Url)
+		return
+	}
+
+	fmt.Printf("%s is fetched with:\n", feed.Url)
+
+	if auth.Username != "" {
+		fmt.Printf("- basic auth as %s\n", auth.Username)
+	}
+	if auth.BearerToken != "" {
+		fmt.Println("- a bearer token")
+	}
+	for _, cookie := range auth.Cookies {
+		name, _, _ := strings.Cut(cookie, "=")
+		fmt.Printf("- cookie %s\n", name)
+	}
+	for name := range auth.Headers {
+		fmt.Printf("- header %s\n", name)
+	}
+}
@@ -0,0 +1,106 @@
+package agg
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestSealFeedAuth(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	feedID := uuid.New()
+
+	auth := &feedAuth{
+		Username:    "bob",
+		Password:    "secret",
+		BearerToken: "token",
+		Cookies:     []string{"session=abc"},
+		Headers:     map[string]string{"X-Api-Key": "def"},
+	}
+
+	sealed, err := sealFeedAuth(key, feedID, auth)
+
+	if err != nil {
+		t.Fatalf("sealFeedAuth() error = %v", err)
+	}
+
+	if bytes.Contains(sealed, []byte("secret")) {
+		t.Fatal("sealed credentials contain the password")
+	}
+
+	tampered := bytes.Clone(sealed)
+	tampered[len(tampered)-1] ^= 1
+
+	tests := []struct {
+		name    string
+		key     []byte
+		feedID  uuid.UUID
+		sealed  []byte
+		wantErr bool
+	}{
+		{name: "same key and feed", key: key, feedID: feedID, sealed: sealed},
+		{name: "other feed", key: key, feedID: uuid.New(), sealed: sealed, wantErr: true},
+		{name: "other key", key: bytes.Repeat([]byte{2}, 32), feedID: feedID, sealed: sealed, wantErr: true},
+		{name: "tampered", key: key, feedID: feedID, sealed: tampered, wantErr: true},
+		{name: "truncated", key: key, feedID: feedID, sealed: sealed[:4], wantErr: true},
+		{name: "invalid key", key: []byte("short"), feedID: feedID, sealed: sealed, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opened, err := openFeedAuth(test.key, test.feedID, test.sealed)
+
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("openFeedAuth() should fail")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("openFeedAuth() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(opened, auth) {
+				t.Errorf("openFeedAuth() = %+v, want %+v", opened, auth)
+			}
+		})
+	}
+}
+
+func TestFetchFeedRedirectDropsHeaders(t *testing.T) {
+	var got http.Header
+
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		io.WriteString(w, testFeed)
+	}))
+	defer other.Close()
+
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != "def" {
+			t.Errorf("X-Api-Key = %q on the original host", r.Header.Get("X-Api-Key"))
+		}
+
+		http.Redirect(w, r, other.URL, http.StatusFound)
+	}))
+	defer origin.Close()
+
+	auth := &feedAuth{Headers: map[string]string{"X-Api-Key": "def"}}
+
+	_, err := fetchFeed(context.Background(), origin.Client(), origin.URL, cacheValidators{}, auth, feedLimits{})
+
+	if err != nil {
+		t.Fatalf("fetchFeed() error = %v", err)
+	}
+
+	if got.Get("X-Api-Key") != "" {
+		t.Errorf("X-Api-Key = %q after a redirect to another host", got.Get("X-Api-Key"))
+	}
+}
@@ -0,0 +1,191 @@
+package agg
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+const (
+	defaultFeedMaxBytes = 10 << 20
+	defaultFeedMaxItems = 1000
+)
+
+// acceptEncoding lists the compressions fetchFeed decodes itself. Setting
+// Accept-Encoding turns off the transparent gzip of net/http, which only
+// knows gzip.
+const acceptEncoding = "gzip, deflate, br"
+
+// feedLimits bound what a single feed may cost. MaxBytes counts the
+// decompressed body so a small compressed body cannot expand without limit.
+// Zero means no limit.
+type feedLimits struct {
+	MaxBytes int64
+	MaxItems int
+}
+
+type feedTooLargeError struct {
+	Limit int64
+}
+
+func (e *feedTooLargeError) Error() string {
+	return fmt.Sprintf("feed is larger than the limit of %s", formatBytes(e.Limit))
+}
+
+// truncatedFeedError is returned when a feed body ends before the document
+// does, eg: when the connection is cut or the server stops writing.
+type truncatedFeedError struct {
+	Read     int64
+	Expected int64
+	err      error
+}
+
+func (e *truncatedFeedError) Error() string {
+	if e.Expected > e.Read {
+		return fmt.Sprintf("feed is truncated, got %s of %s: %v", formatBytes(e.Read), formatBytes(e.Expected), e.err)
+	}
+
+	return fmt.Sprintf("feed is truncated, the body ends after %s before the document does: %v", formatBytes(e.Read), e.err)
+}
+
+func (e *truncatedFeedError) Unwrap() error {
+	return e.err
+}
+
+// decompress decodes a body sent with the given Content-Encoding.
+func decompress(body io.Reader, contentEncoding string) (io.Reader, error) {
+	switch strings.ToLower(strings.TrimSpace(contentEncoding)) {
+	case "", "identity":
+		return body, nil
+	case "gzip", "x-gzip":
+		return gzip.NewReader(body)
+	case "br":
+		return brotli.NewReader(body), nil
+	case "deflate":
+		// deflate is meant to be zlib wrapped, but some servers send raw
+		// deflate data
+		buffered := bufio.NewReader(body)
+		header, err := buffered.Peek(2)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+			return zlib.NewReader(buffered)
+		}
+
+		return flate.NewReader(buffered), nil
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", contentEncoding)
+	}
+}
+
+// countingReader counts the bytes read from the wire and remembers the
+// first read error, which tells a cut connection from a broken document.
+type countingReader struct {
+	r   io.Reader
+	n   int64
+	err error
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+
+	if err != nil && !errors.Is(err, io.EOF) && c.err == nil {
+		c.err = err
+	}
+
+	return n, err
+}
+
+// maxBytesReader fails with a feedTooLargeError once more than limit bytes
+// are read.
+type maxBytesReader struct {
+	r         io.Reader
+	limit     int64
+	remaining int64
+	err       error
+}
+
+func newMaxBytesReader(r io.Reader, limit int64) io.Reader {
+	if limit <= 0 {
+		return r
+	}
+
+	return &maxBytesReader{r: r, limit: limit, remaining: limit}
+}
+
+func (m *maxBytesReader) Read(p []byte) (int, error) {
+	if m.err != nil {
+		return 0, m.err
+	}
+
+	// read one byte past the limit to tell a body of exactly limit bytes
+	// from a larger one
+	if int64(len(p)) > m.remaining+1 {
+		p = p[:m.remaining+1]
+	}
+
+	n, err := m.r.Read(p)
+
+	if int64(n) <= m.remaining {
+		m.remaining -= int64(n)
+		return n, err
+	}
+
+	m.err = &feedTooLargeError{Limit: m.limit}
+
+	return int(m.remaining), m.err
+}
+
+// cappedBuffer keeps the start of a body for the fetch log.
+type cappedBuffer struct {
+	data []byte
+	max  int
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - len(b.data); room > 0 {
+		b.data = append(b.data, p[:min(room, len(p))]...)
+	}
+
+	return len(p), nil
+}
+
+// bodyError explains why reading a feed body failed: it was too large, it
+// was cut short, or it is not a feed we can read.
+func bodyError(err error, wire *countingReader, contentLength int64) error {
+	var tooLarge *feedTooLargeError
+
+	if errors.As(err, &tooLarge) {
+		return err
+	}
+
+	if wire.err != nil {
+		return &truncatedFeedError{Read: wire.n, Expected: contentLength, err: wire.err}
+	}
+
+	var syntaxErr *xml.SyntaxError
+	var jsonSyntaxErr *json.SyntaxError
+
+	unexpectedEOF := errors.Is(err, io.ErrUnexpectedEOF) ||
+		(errors.As(err, &syntaxErr) && syntaxErr.Msg == "unexpected EOF") ||
+		(errors.As(err, &jsonSyntaxErr) && jsonSyntaxErr.Error() == "unexpected end of JSON input")
+
+	if unexpectedEOF {
+		return &truncatedFeedError{Read: wire.n, Expected: contentLength, err: err}
+	}
+
+	return &parseError{err: err}
+}
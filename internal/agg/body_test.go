@@ -0,0 +1,282 @@
+package agg
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMaxBytesReader(t *testing.T) {
+	tests := []struct {
+		name     string
+		size     int
+		limit    int64
+		want     int
+		tooLarge bool
+	}{
+		{name: "no limit", size: 100, limit: 0, want: 100},
+		{name: "under the limit", size: 99, limit: 100, want: 99},
+		{name: "at the limit", size: 100, limit: 100, want: 100},
+		{name: "over the limit", size: 101, limit: 100, want: 100, tooLarge: true},
+		{name: "far over the limit", size: 1 << 20, limit: 100, want: 100, tooLarge: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reader := newMaxBytesReader(bytes.NewReader(make([]byte, test.size)), test.limit)
+
+			got, err := io.ReadAll(reader)
+
+			if len(got) != test.want {
+				t.Errorf("read %d bytes, want %d", len(got), test.want)
+			}
+
+			var tooLarge *feedTooLargeError
+
+			if errors.As(err, &tooLarge) != test.tooLarge {
+				t.Fatalf("error = %v, want a feedTooLargeError: %v", err, test.tooLarge)
+			}
+
+			if test.tooLarge && tooLarge.Limit != test.limit {
+				t.Errorf("limit = %d, want %d", tooLarge.Limit, test.limit)
+			}
+
+			if !test.tooLarge && err != nil {
+				t.Errorf("error = %v", err)
+			}
+		})
+	}
+}
+
+const testFeed = `<?xml version="1.0"?>
+<rss version="2.0"><channel><title>Test</title>
+<item><title>one</title><link>https://example.com/1</link></item>
+<item><title>two</title><link>https://example.com/2</link></item>
+<item><title>three</title><link>https://example.com/3</link></item>
+</channel></rss>
+`
+
+func gzipped(t *testing.T, data []byte) []byte {
+	var buf bytes.Buffer
+
+	writer := gzip.NewWriter(&buf)
+
+	_, err := writer.Write(data)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = writer.Close()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestFetchFeedBody(t *testing.T) {
+	bomb := gzipped(t, []byte("<rss><channel>"+strings.Repeat(" ", 1<<20)+"</channel></rss>"))
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		limits  feedLimits
+		items   int
+		dropped int
+		check   func(t *testing.T, err error)
+	}{
+		{
+			name: "whole feed",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				io.WriteString(w, testFeed)
+			},
+			items: 3,
+		},
+		{
+			name: "gzip",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Encoding", "gzip")
+				w.Write(gzipped(t, []byte(testFeed)))
+			},
+			items: 3,
+		},
+		{
+			name: "item limit",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				io.WriteString(w, testFeed)
+			},
+			limits:  feedLimits{MaxItems: 2},
+			items:   2,
+			dropped: 1,
+		},
+		{
+			name: "decompressed size limit",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Encoding", "gzip")
+				w.Write(bomb)
+			},
+			limits: feedLimits{MaxBytes: 64 << 10},
+			check: func(t *testing.T, err error) {
+				var tooLarge *feedTooLargeError
+
+				if !errors.As(err, &tooLarge) {
+					t.Fatalf("error = %v, want a feedTooLargeError", err)
+				}
+
+				if err.Error() != "feed is larger than the limit of 64.0 KiB" {
+					t.Errorf("error = %q", err)
+				}
+			},
+		},
+		{
+			name: "connection cut",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Length", "2048")
+				io.WriteString(w, testFeed[:100])
+			},
+			check: func(t *testing.T, err error) {
+				var truncated *truncatedFeedError
+
+				if !errors.As(err, &truncated) {
+					t.Fatalf("error = %v, want a truncatedFeedError", err)
+				}
+
+				if truncated.Read != 100 || truncated.Expected != 2048 {
+					t.Errorf("got %d of %d bytes, want 100 of 2048", truncated.Read, truncated.Expected)
+				}
+
+				if !strings.HasPrefix(err.Error(), "feed is truncated, got 100 B of 2.0 KiB") {
+					t.Errorf("error = %q", err)
+				}
+			},
+		},
+		{
+			name: "document cut",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				io.WriteString(w, testFeed[:120])
+			},
+			check: func(t *testing.T, err error) {
+				var truncated *truncatedFeedError
+
+				if !errors.As(err, &truncated) {
+					t.Fatalf("error = %v, want a truncatedFeedError", err)
+				}
+
+				if !strings.HasPrefix(err.Error(), "feed is truncated, the body ends after 120 B before the document does") {
+					t.Errorf("error = %q", err)
+				}
+			},
+		},
+		{
+			name: "json document cut",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/feed+json")
+				io.WriteString(w, testJSONFeed[:120])
+			},
+			check: func(t *testing.T, err error) {
+				var truncated *truncatedFeedError
+
+				if !errors.As(err, &truncated) {
+					t.Fatalf("error = %v, want a truncatedFeedError", err)
+				}
+			},
+		},
+		{
+			name: "not a feed",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				io.WriteString(w, "<html><body>hello</body></html>")
+			},
+			check: func(t *testing.T, err error) {
+				var parseErr *parseError
+
+				if !errors.As(err, &parseErr) {
+					t.Fatalf("error = %v, want a parseError", err)
+				}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(test.handler)
+			defer server.Close()
+
+			result, err := fetchFeed(context.Background(), server.Client(), server.URL, cacheValidators{}, nil, test.limits)
+
+			if test.check != nil {
+				test.check(t, err)
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("fetchFeed() error = %v", err)
+			}
+
+			if len(result.Feed.Channel.Item) != test.items || result.DroppedItems != test.dropped {
+				t.Errorf("got %d items and %d dropped, want %d and %d", len(result.Feed.Channel.Item), result.DroppedItems, test.items, test.dropped)
+			}
+		})
+	}
+}
+
+const testJSONFeed = `{
+  "version": "https://jsonfeed.org/version/1.1",
+  "title": "Test",
+  "items": [
+    {"id": "1", "url": "https://example.com/1"},
+    {"id": "2", "url": "https://example.com/2"},
+    {"id": "3", "url": "https://example.com/3", "attachments": [{"url": "a.mp3"}]}
+  ],
+  "home_page_url": "https://example.com/"
+}
+`
+
+func TestDecodeFeedMaxItems(t *testing.T) {
+	documents := []struct {
+		name        string
+		body        string
+		contentType string
+		link        string
+	}{
+		{name: "rss", body: testFeed, contentType: "application/rss+xml"},
+		{name: "json", body: testJSONFeed, contentType: "application/feed+json", link: "https://example.com/"},
+	}
+
+	tests := []struct {
+		maxItems int
+		items    int
+		dropped  int
+	}{
+		{maxItems: 0, items: 3},
+		{maxItems: 3, items: 3},
+		{maxItems: 2, items: 2, dropped: 1},
+		{maxItems: 1, items: 1, dropped: 2},
+	}
+
+	for _, document := range documents {
+		for _, test := range tests {
+			feed, dropped, err := decodeFeed(strings.NewReader(document.body), document.contentType, "https://example.com/feed", test.maxItems)
+
+			if err != nil {
+				t.Fatalf("%s: decodeFeed() error = %v", document.name, err)
+			}
+
+			if len(feed.Channel.Item) != test.items || dropped != test.dropped {
+				t.Errorf("%s, max %d: got %d items and %d dropped, want %d and %d", document.name, test.maxItems, len(feed.Channel.Item), dropped, test.items, test.dropped)
+			}
+
+			// fields after the items are still read
+			if feed.Channel.Title != "Test" || feed.Channel.Link != document.link {
+				t.Errorf("%s, max %d: title = %q, link = %q", document.name, test.maxItems, feed.Channel.Title, feed.Channel.Link)
+			}
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package agg
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"mime"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/transform"
+)
+
+var xmlDeclEncoding = regexp.MustCompile(`^(\s*<\?xml[^>]*?encoding\s*=\s*)["'][^"']*["']`)
+
+// xmlDeclPeek is how much of a body is looked at for its xml declaration.
+const xmlDeclPeek = 1024
+
+// newXMLDecoder returns a decoder that understands every encoding an xml
+// declaration may name, not just UTF-8.
+func newXMLDecoder(body io.Reader) *xml.Decoder {
+	decoder := xml.NewDecoder(body)
+	decoder.CharsetReader = charset.NewReaderLabel
+
+	return decoder
+}
+
+// utf8Reader applies the charset of the Content-Type header, which takes
+// precedence over the xml declaration. Servers commonly claim UTF-8 for
+// everything, so bytes that are not valid UTF-8 in a body with no charset
+// of its own are read as Windows-1252, by far the most common culprit.
+// Bodies that only declare their encoding in the xml declaration are left
+// to the decoder.
+func utf8Reader(body *bufio.Reader, contentType string) (io.Reader, error) {
+	label := ""
+
+	_, params, err := mime.ParseMediaType(contentType)
+	if err == nil {
+		label = strings.ToLower(strings.TrimSpace(params["charset"]))
+	}
+
+	if label == "utf8" {
+		label = "utf-8"
+	}
+
+	if label == "" {
+		prefix, err := body.Peek(xmlDeclPeek)
+
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+			return nil, err
+		}
+
+		if xmlDeclEncoding.Match(prefix) {
+			return body, nil
+		}
+	}
+
+	var decoder transform.Transformer = windows1252Fallback{}
+
+	if label != "" && label != "utf-8" {
+		encoding, _ := charset.Lookup(label)
+
+		if encoding == nil {
+			return body, nil
+		}
+
+		decoder = encoding.NewDecoder()
+	}
+
+	return declareUTF8(transform.NewReader(body, decoder))
+}
+
+// declareUTF8 rewrites the encoding in the xml declaration of a body that
+// is being converted, so the decoder does not convert it a second time.
+func declareUTF8(body io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(body)
+
+	prefix, err := buffered.Peek(xmlDeclPeek)
+
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return nil, err
+	}
+
+	head := xmlDeclEncoding.ReplaceAll(prefix, []byte(`${1}"UTF-8"`))
+
+	_, err = buffered.Discard(len(prefix))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return io.MultiReader(bytes.NewReader(head), buffered), nil
+}
+
+// windows1252Fallback passes valid UTF-8 through and reads every byte that
+// is not part of a valid UTF-8 sequence as Windows-1252.
+type windows1252Fallback struct {
+	transform.NopResetter
+}
+
+func (windows1252Fallback) Transform(dst []byte, src []byte, atEOF bool) (int, int, error) {
+	nDst, nSrc := 0, 0
+
+	for nSrc < len(src) {
+		r, size := utf8.DecodeRune(src[nSrc:])
+
+		if r == utf8.RuneError && size <= 1 {
+			if !atEOF && !utf8.FullRune(src[nSrc:]) {
+				return nDst, nSrc, transform.ErrShortSrc
+			}
+
+			r = charmap.Windows1252.DecodeByte(src[nSrc])
+			size = 1
+		}
+
+		if nDst+utf8.UTFMax > len(dst) {
+			return nDst, nSrc, transform.ErrShortDst
+		}
+
+		if size > 1 || src[nSrc] < utf8.RuneSelf {
+			nDst += copy(dst[nDst:], src[nSrc:nSrc+size])
+		} else {
+			nDst += utf8.EncodeRune(dst[nDst:], r)
+		}
+
+		nSrc += size
+	}
+
+	return nDst, nSrc, nil
+}
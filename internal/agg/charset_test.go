@@ -0,0 +1,165 @@
+package agg
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/transform"
+)
+
+func TestParseFeedCharset(t *testing.T) {
+	charsets := []struct {
+		label    string
+		encoding encoding.Encoding
+		title    string
+		// mislabelled is what the title reads as when the body is sent as
+		// UTF-8, empty when it is only expected to come out as valid UTF-8
+		mislabelled string
+	}{
+		{label: "ISO-8859-1", encoding: charmap.ISO8859_1, title: "Café à la crème", mislabelled: "Café à la crème"},
+		{label: "windows-1252", encoding: charmap.Windows1252, title: "“Smart” quotes – €5", mislabelled: "“Smart” quotes – €5"},
+		{label: "Shift_JIS", encoding: japanese.ShiftJIS, title: "日本語のフィード"},
+		{label: "KOI8-R", encoding: charmap.KOI8R, title: "Новости", mislabelled: "îÏ×ÏÓÔÉ"},
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		declared    string
+		mislabelled bool
+	}{
+		{name: "header", contentType: "application/rss+xml; charset=%s"},
+		{name: "declaration", contentType: "application/rss+xml", declared: "%s"},
+		// the header wins when the two disagree
+		{name: "header and declaration disagree", contentType: "application/rss+xml; charset=%s", declared: "windows-1251"},
+		{name: "mislabelled utf-8", contentType: "application/rss+xml; charset=utf-8", mislabelled: true},
+		{name: "no charset at all", contentType: "application/rss+xml", mislabelled: true},
+	}
+
+	for _, cs := range charsets {
+		for _, test := range tests {
+			t.Run(cs.label+"/"+test.name, func(t *testing.T) {
+				contentType := strings.ReplaceAll(test.contentType, "%s", cs.label)
+				declared := strings.ReplaceAll(test.declared, "%s", cs.label)
+
+				declaration := `<?xml version="1.0"?>`
+				if declared != "" {
+					declaration = `<?xml version="1.0" encoding="` + declared + `"?>`
+				}
+
+				document := declaration + "\n<rss version=\"2.0\"><channel><title>" + cs.title + "</title></channel></rss>\n"
+
+				body, err := cs.encoding.NewEncoder().Bytes([]byte(document))
+
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				feed, err := parseFeed(body, contentType, "https://example.com/feed")
+
+				if err != nil {
+					t.Fatalf("parseFeed() error = %v", err)
+				}
+
+				got := feed.Channel.Title
+
+				want := cs.title
+				if test.mislabelled {
+					want = cs.mislabelled
+				}
+
+				if !utf8.ValidString(got) {
+					t.Fatalf("title %q is not valid UTF-8", got)
+				}
+
+				if want != "" && got != want {
+					t.Errorf("title = %q, want %q", got, want)
+				}
+
+				if want == "" && got == cs.title {
+					t.Errorf("title = %q, mislabelled %s should not decode", got, cs.label)
+				}
+			})
+		}
+	}
+}
+
+func TestWindows1252Fallback(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []byte
+		want string
+	}{
+		{name: "ascii", in: []byte("plain"), want: "plain"},
+		{name: "utf-8", in: []byte("héllo ✓ 日本"), want: "héllo ✓ 日本"},
+		{name: "windows-1252", in: []byte("\x93quoted\x94 \x80"), want: "“quoted” €"},
+		{name: "mixed", in: []byte("caf\xe9 and café ✓"), want: "café and café ✓"},
+		{name: "truncated sequence at the end", in: []byte("ok \xe2\x9c"), want: "ok âœ"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// one byte at a time so multibyte sequences are split across reads
+			reader := transform.NewReader(iotest.OneByteReader(bytes.NewReader(test.in)), windows1252Fallback{})
+
+			got, err := io.ReadAll(reader)
+
+			if err != nil {
+				t.Fatalf("read error = %v", err)
+			}
+
+			if string(got) != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDeclareUTF8(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{
+			in:   `<?xml version="1.0" encoding="KOI8-R"?><rss/>`,
+			want: `<?xml version="1.0" encoding="UTF-8"?><rss/>`,
+		},
+		{
+			in:   "\n  <?xml version='1.0' encoding = 'Shift_JIS' standalone='yes'?><rss/>",
+			want: "\n  <?xml version='1.0' encoding = \"UTF-8\" standalone='yes'?><rss/>",
+		},
+		{
+			in:   `<?xml version="1.0"?><rss/>`,
+			want: `<?xml version="1.0"?><rss/>`,
+		},
+		{
+			in:   `<rss><channel><title>encoding="latin1"</title></channel></rss>`,
+			want: `<rss><channel><title>encoding="latin1"</title></channel></rss>`,
+		},
+	}
+
+	for _, test := range tests {
+		reader, err := declareUTF8(strings.NewReader(test.in))
+
+		if err != nil {
+			t.Fatalf("declareUTF8() error = %v", err)
+		}
+
+		got, err := io.ReadAll(reader)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(got) != test.want {
+			t.Errorf("declareUTF8(%q) = %q, want %q", test.in, got, test.want)
+		}
+	}
+}
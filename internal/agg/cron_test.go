@@ -0,0 +1,73 @@
+package agg
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	// a sunday
+	from := time.Date(2026, 10, 18, 22, 50, 0, 0, time.UTC)
+
+	tests := []struct {
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{"* * * * *", from, time.Date(2026, 10, 18, 22, 51, 0, 0, time.UTC)},
+		{"*/15 7-22 * * *", from, time.Date(2026, 10, 19, 7, 0, 0, 0, time.UTC)},
+		{"*/15 7-22 * * *", from.Add(-10 * time.Minute), time.Date(2026, 10, 18, 22, 45, 0, 0, time.UTC)},
+		{"0 9 * * mon-fri", from, time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)},
+		{"30 8 * * sat,sun", from, time.Date(2026, 10, 24, 8, 30, 0, 0, time.UTC)},
+		{"0 0 * * 7", from, time.Date(2026, 10, 25, 0, 0, 0, 0, time.UTC)},
+		{"0 12 1 jan *", from, time.Date(2027, 1, 1, 12, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", from, time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// the day of month and day of week match either way when both are set
+		{"0 6 1 * mon", from, time.Date(2026, 10, 19, 6, 0, 0, 0, time.UTC)},
+		{"5,35 */6 * * *", from, time.Date(2026, 10, 19, 0, 5, 0, 0, time.UTC)},
+		{"@hourly", from, time.Date(2026, 10, 18, 23, 0, 0, 0, time.UTC)},
+		{"@daily", from, time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", from, time.Date(2026, 10, 25, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", from, time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", from, time.Time{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			schedule, err := parseCron(test.expr)
+
+			if err != nil {
+				t.Fatalf("parseCron(%q) error = %v", test.expr, err)
+			}
+
+			got := schedule.next(test.from)
+
+			if !got.Equal(test.want) {
+				t.Errorf("next(%v) = %v, want %v", test.from, got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseCronInvalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"* * * foo *",
+		"@often",
+	} {
+		_, err := parseCron(expr)
+
+		if err == nil {
+			t.Errorf("parseCron(%q) should fail", expr)
+		}
+	}
+}
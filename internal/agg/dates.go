@@ -0,0 +1,163 @@
+package agg
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// maxFutureSkew is how far ahead of now a publish date may be before it is
+// considered bogus and clamped, which tolerates publishers with bad clocks
+// without letting an item sit at the top of browse for years.
+const maxFutureSkew = 24 * time.Hour
+
+// dateLayouts are the publish date formats seen in the wild. Dates are
+// normalized by normalizeDate first, so day names are already stripped and
+// named zones replaced by numeric offsets.
+var dateLayouts = []string{
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04 -0700",
+	"2 Jan 06 15:04:05 -0700",
+	"2 Jan 06 15:04 -0700",
+	"2 January 2006 15:04:05 -0700",
+	"2 January 2006 15:04 -0700",
+	"2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04 MST",
+	"2 Jan 06 15:04:05 MST",
+	"2 Jan 06 15:04 MST",
+	"2 Jan 2006 15:04:05",
+	"2 Jan 2006",
+	"Jan 2 2006 15:04:05 -0700",
+	"Jan 2 15:04:05 2006",
+	"Jan 2 15:04:05 MST 2006",
+	"Jan 2 15:04:05 -0700 2006",
+	"January 2 2006",
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05.999999999Z0700",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"20060102T150405Z0700",
+}
+
+// zoneOffsets maps the zone names that show up in feeds to their offsets.
+// time.Parse only knows the local zone's abbreviation and gives any other
+// name a zero offset.
+var zoneOffsets = map[string]int{
+	"UT":   0,
+	"UTC":  0,
+	"GMT":  0,
+	"Z":    0,
+	"EST":  -5 * 3600,
+	"EDT":  -4 * 3600,
+	"CST":  -6 * 3600,
+	"CDT":  -5 * 3600,
+	"MST":  -7 * 3600,
+	"MDT":  -6 * 3600,
+	"PST":  -8 * 3600,
+	"PDT":  -7 * 3600,
+	"AKST": -9 * 3600,
+	"AKDT": -8 * 3600,
+	"HST":  -10 * 3600,
+	"BST":  1 * 3600,
+	"WET":  0,
+	"WEST": 1 * 3600,
+	"CET":  1 * 3600,
+	"CEST": 2 * 3600,
+	"MET":  1 * 3600,
+	"MEST": 2 * 3600,
+	"EET":  2 * 3600,
+	"EEST": 3 * 3600,
+	"MSK":  3 * 3600,
+	"IST":  5*3600 + 1800,
+	"SGT":  8 * 3600,
+	"HKT":  8 * 3600,
+	"JST":  9 * 3600,
+	"KST":  9 * 3600,
+	"AEST": 10 * 3600,
+	"AEDT": 11 * 3600,
+	"NZST": 12 * 3600,
+	"NZDT": 13 * 3600,
+}
+
+var (
+	dayNamePrefix = regexp.MustCompile(`(?i)^(mon|tue|wed|thu|fri|sat|sun)[a-z]*\.?,?\s*`)
+	ordinalSuffix = regexp.MustCompile(`(\d)(st|nd|rd|th)\b`)
+	whitespace    = regexp.MustCompile(`\s+`)
+	zoneComment   = regexp.MustCompile(`(\d{4}) \([A-Za-z]+\)$`)
+)
+
+// parsePublishDate parses a feed date in any of the formats in dateLayouts.
+// Missing or unparseable dates fall back to firstSeen and dates too far in
+// the future are clamped to it, so one bad date never drops an item.
+func parsePublishDate(date string, firstSeen time.Time) time.Time {
+	publishedAt, err := parseDate(date)
+
+	if err != nil {
+		return firstSeen
+	}
+
+	if publishedAt.After(firstSeen.Add(maxFutureSkew)) {
+		return firstSeen
+	}
+
+	return publishedAt
+}
+
+func parseDate(date string) (time.Time, error) {
+	normalized := normalizeDate(date)
+
+	if normalized == "" {
+		return time.Time{}, fmt.Errorf("missing date")
+	}
+
+	for _, layout := range dateLayouts {
+		parsed, err := time.Parse(layout, normalized)
+
+		if err == nil {
+			return parsed, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unrecognized date format: %q", date)
+}
+
+func normalizeDate(date string) string {
+	date = whitespace.ReplaceAllString(strings.TrimSpace(date), " ")
+
+	// the day name is redundant and often wrong or misspelt
+	date = dayNamePrefix.ReplaceAllString(date, "")
+
+	date = ordinalSuffix.ReplaceAllString(date, "$1")
+	date = strings.ReplaceAll(date, ",", "")
+
+	// eg: +0000 (UTC)
+	date = zoneComment.ReplaceAllString(date, "$1")
+
+	fields := strings.Split(date, " ")
+	last := strings.Trim(fields[len(fields)-1], "()")
+
+	if offset, ok := zoneOffsets[strings.ToUpper(last)]; ok && len(fields) > 1 {
+		fields[len(fields)-1] = formatOffset(offset)
+		date = strings.Join(fields, " ")
+	}
+
+	return date
+}
+
+func formatOffset(offset int) string {
+	sign := '+'
+	if offset < 0 {
+		sign = '-'
+		offset = -offset
+	}
+
+	return fmt.Sprintf("%c%02d%02d", sign, offset/3600, offset%3600/60)
+}
@@ -0,0 +1,82 @@
+package agg
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		date string
+		want string
+	}{
+		{"Mon, 02 Jan 2006 15:04:05 -0700", "2006-01-02T15:04:05-07:00"},
+		{"Mon, 02 Jan 2006 15:04:05 GMT", "2006-01-02T15:04:05Z"},
+		{"Mon, 2 Jan 2006 15:04:05 EST", "2006-01-02T15:04:05-05:00"},
+		{"Tuesday, 3 January 2006 10:00:00 CEST", "2006-01-03T10:00:00+02:00"},
+		{"Wed, 04 Jan 06 08:30 +0000", "2006-01-04T08:30:00Z"},
+		{"  Thu,   5 Jan  2006   12:00:00   PST ", "2006-01-05T12:00:00-08:00"},
+		{"Fri, 06 Jan 2006 12:00:00 +0000 (UTC)", "2006-01-06T12:00:00Z"},
+		{"Sat, 7th Jan 2006 09:15:00 IST", "2006-01-07T09:15:00+05:30"},
+		{"2006-01-08T15:04:05Z", "2006-01-08T15:04:05Z"},
+		{"2006-01-08T15:04:05.123+01:00", "2006-01-08T15:04:05.123+01:00"},
+		{"2006-01-08T15:04:05+0100", "2006-01-08T15:04:05+01:00"},
+		{"2006-01-08 15:04:05", "2006-01-08T15:04:05Z"},
+		{"2006-01-08", "2006-01-08T00:00:00Z"},
+		{"January 9, 2006", "2006-01-09T00:00:00Z"},
+		{"20060110T150405Z", "2006-01-10T15:04:05Z"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.date, func(t *testing.T) {
+			got, err := parseDate(test.date)
+
+			if err != nil {
+				t.Fatalf("parseDate(%q) error = %v", test.date, err)
+			}
+
+			want, err := time.Parse(time.RFC3339Nano, test.want)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !got.Equal(want) {
+				t.Errorf("parseDate(%q) = %v, want %v", test.date, got, want)
+			}
+		})
+	}
+}
+
+func TestParseDateInvalid(t *testing.T) {
+	for _, date := range []string{"", "   ", "yesterday", "32 Jan 2006", "2006-13-01"} {
+		_, err := parseDate(date)
+
+		if err == nil {
+			t.Errorf("parseDate(%q) should fail", date)
+		}
+	}
+}
+
+func TestParsePublishDate(t *testing.T) {
+	firstSeen := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		date string
+		want time.Time
+	}{
+		{"2026-10-17T12:00:00Z", time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)},
+		{"2026-10-19T06:00:00Z", time.Date(2026, 10, 19, 6, 0, 0, 0, time.UTC)},
+		{"2027-01-01T00:00:00Z", firstSeen},
+		{"not a date", firstSeen},
+		{"", firstSeen},
+	}
+
+	for _, test := range tests {
+		got := parsePublishDate(test.date, firstSeen)
+
+		if !got.Equal(test.want) {
+			t.Errorf("parsePublishDate(%q) = %v, want %v", test.date, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package agg
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// maxDiscoveryBytes bounds how much of a web page is read when looking for
+// its feeds.
+const maxDiscoveryBytes = 5 << 20
+
+// feedLinkTypes are the link types a page uses to advertise its feeds.
+var feedLinkTypes = []string{
+	"application/rss+xml",
+	"application/atom+xml",
+	"application/rdf+xml",
+	"application/feed+json",
+	"application/json",
+}
+
+// commonFeedPaths are tried when a page does not advertise any feed.
+var commonFeedPaths = []string{
+	"/feed",
+	"/rss.xml",
+	"/atom.xml",
+	"/feed.xml",
+	"/index.xml",
+	"/rss",
+	"/feed.json",
+}
+
+type discoveredFeed struct {
+	URL   string
+	Title string
+	Type  string
+}
+
+// resolveFeedURL returns the url of the feed to add for the given url. Feeds
+// are returned as is, while for web pages the feeds they advertise, or live
+// at a common path, are looked up and the user is asked to pick one when
+// there are several.
+func resolveFeedURL(ctx context.Context, client *http.Client, pageURL string) (string, error) {
+	body, contentType, finalURL, err := fetchPage(ctx, client, pageURL)
+
+	if err != nil && isUnreachable(err) {
+		// private feeds only answer once their credentials are set with feed
+		// auth, which needs the feed to be added first
+		fmt.Printf("Could not check %s (%v), adding it as is\n", pageURL, err)
+		return pageURL, nil
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	_, err = parseFeed(body, contentType, finalURL)
+
+	if err == nil {
+		return pageURL, nil
+	}
+
+	if !isHTML(contentType, body) {
+		return "", fmt.Errorf("%s is neither a feed nor a web page: %w", pageURL, err)
+	}
+
+	feeds := findFeedLinks(body, finalURL)
+
+	if len(feeds) == 0 {
+		feeds = probeFeedPaths(ctx, client, finalURL)
+	}
+
+	switch len(feeds) {
+	case 0:
+		return "", fmt.Errorf("no feed found at %s", pageURL)
+	case 1:
+		fmt.Printf("Found feed %s\n", feeds[0].URL)
+		return feeds[0].URL, nil
+	default:
+		return pickFeed(feeds, os.Stdin)
+	}
+}
+
+func fetchPage(ctx context.Context, client *http.Client, pageURL string) ([]byte, string, string, error) {
+	request, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
+
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	response, err := client.Do(request)
+
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, "", "", &statusError{StatusCode: response.StatusCode}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, maxDiscoveryBytes))
+
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	return body, response.Header.Get("Content-Type"), response.Request.URL.String(), nil
+}
+
+// isUnreachable tells whether a url could not be checked because it needs
+// credentials or its server is busy or down, rather than because it is wrong.
+// dns failures and refused connections are reported, --no-discover adds such
+// a url anyway.
+func isUnreachable(err error) bool {
+	var status *statusError
+
+	if errors.As(err, &status) {
+		switch {
+		case status.StatusCode == http.StatusUnauthorized,
+			status.StatusCode == http.StatusForbidden,
+			status.StatusCode == http.StatusTooManyRequests,
+			status.StatusCode >= 500:
+			return true
+		default:
+			return false
+		}
+	}
+
+	var netErr net.Error
+
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func isHTML(contentType string, body []byte) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+
+	if err == nil && (mediaType == "text/html" || mediaType == "application/xhtml+xml") {
+		return true
+	}
+
+	return http.DetectContentType(body) == "text/html; charset=utf-8"
+}
+
+// findFeedLinks returns the feeds advertised with <link rel="alternate"> in
+// the head of a page.
+func findFeedLinks(body []byte, pageURL string) []discoveredFeed {
+	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+	baseURL := pageURL
+
+	var feeds []discoveredFeed
+	seen := map[string]bool{}
+
+	for {
+		tokenType := tokenizer.Next()
+
+		if tokenType == html.ErrorToken {
+			return feeds
+		}
+
+		if tokenType != html.StartTagToken && tokenType != html.SelfClosingTagToken {
+			continue
+		}
+
+		token := tokenizer.Token()
+
+		switch token.Data {
+		case "body":
+			return feeds
+		case "base":
+			if href := tokenAttr(token, "href"); href != "" {
+				baseURL = resolveLink(pageURL, href)
+			}
+		case "link":
+			rel := strings.Fields(strings.ToLower(tokenAttr(token, "rel")))
+			linkType := strings.ToLower(strings.TrimSpace(tokenAttr(token, "type")))
+			href := strings.TrimSpace(tokenAttr(token, "href"))
+
+			if href == "" || !slices.Contains(rel, "alternate") || !slices.Contains(feedLinkTypes, linkType) {
+				continue
+			}
+
+			feedURL := resolveLink(baseURL, href)
+
+			if seen[feedURL] {
+				continue
+			}
+			seen[feedURL] = true
+
+			feeds = append(feeds, discoveredFeed{
+				URL:   feedURL,
+				Title: strings.TrimSpace(tokenAttr(token, "title")),
+				Type:  linkType,
+			})
+		}
+	}
+}
+
+// probeFeedPaths looks for a feed at the common feed paths of the site.
+func probeFeedPaths(ctx context.Context, client *http.Client, pageURL string) []discoveredFeed {
+	var feeds []discoveredFeed
+
+	for _, path := range commonFeedPaths {
+		feedURL := resolveLink(pageURL, path)
+
+		body, contentType, finalURL, err := fetchPage(ctx, client, feedURL)
+
+		if err != nil {
+			continue
+		}
+
+		feed, err := parseFeed(body, contentType, finalURL)
+
+		if err != nil {
+			continue
+		}
+
+		feeds = append(feeds, discoveredFeed{
+			URL:   feedURL,
+			Title: strings.TrimSpace(feed.Channel.Title),
+		})
+
+		// sites usually serve the same feed at several of these paths
+		break
+	}
+
+	return feeds
+}
+
+func pickFeed(feeds []discoveredFeed, input io.Reader) (string, error) {
+	fmt.Println("Several feeds were found:")
+
+	for i, feed := range feeds {
+		title := feed.Title
+		if title == "" {
+			title = feed.URL
+		}
+
+		fmt.Printf("%d. %s (%s)\n", i+1, title, feed.URL)
+	}
+
+	fmt.Printf("Pick a feed [1-%d]: ", len(feeds))
+
+	line, err := bufio.NewReader(input).ReadString('\n')
+
+	if err != nil && line == "" {
+		return "", fmt.Errorf("no feed picked: %w", err)
+	}
+
+	choice, err := strconv.Atoi(strings.TrimSpace(line))
+
+	if err != nil || choice < 1 || choice > len(feeds) {
+		return "", fmt.Errorf("invalid choice %q", strings.TrimSpace(line))
+	}
+
+	return feeds[choice-1].URL, nil
+}
+
+func tokenAttr(token html.Token, name string) string {
+	for _, attr := range token.Attr {
+		if attr.Key == name {
+			return attr.Val
+		}
+	}
+
+	return ""
+}
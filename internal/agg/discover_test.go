@@ -0,0 +1,58 @@
+package agg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestIsUnreachable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "unauthorized", err: &statusError{StatusCode: 401}, want: true},
+		{name: "forbidden", err: &statusError{StatusCode: 403}, want: true},
+		{name: "rate limited", err: &statusError{StatusCode: 429}, want: true},
+		{name: "server error", err: &statusError{StatusCode: 503}, want: true},
+		{name: "not found", err: &statusError{StatusCode: 404}, want: false},
+		{
+			name: "timeout",
+			err:  &url.Error{Op: "Get", URL: "https://example.com", Err: context.DeadlineExceeded},
+			want: true,
+		},
+		{
+			name: "dial timeout",
+			err:  &net.OpError{Op: "dial", Net: "tcp", Err: os.ErrDeadlineExceeded},
+			want: true,
+		},
+		{
+			name: "host not found",
+			err:  &net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: "nope.invalid", IsNotFound: true}},
+			want: false,
+		},
+		{
+			name: "connection refused",
+			err:  &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)},
+			want: false,
+		},
+		{name: "other error", err: errors.New("boom"), want: false},
+		{name: "wrapped", err: fmt.Errorf("fetching: %w", &statusError{StatusCode: 401}), want: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := isUnreachable(test.err)
+
+			if got != test.want {
+				t.Errorf("isUnreachable(%v) = %v, want %v", test.err, got, test.want)
+			}
+		})
+	}
+}
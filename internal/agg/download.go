@@ -0,0 +1,499 @@
+package agg
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/samuelea/gator/internal/config"
+	"github.com/samuelea/gator/internal/database"
+)
+
+const defaultDownloadDir = "gator-downloads"
+
+var errDownloadTooLarge = errors.New("download exceeds the size limit")
+
+var errDownloadStalled = errors.New("download stalled")
+
+func DownloadHandler(ctx context.Context, state *config.State, command config.Command, user database.User) error {
+	flags := flag.NewFlagSet("download", flag.ContinueOnError)
+	dir := flags.String("dir", state.Config.DownloadDir, "directory enclosures are downloaded to")
+	maxBytes := flags.Int64("max-bytes", state.Config.DownloadMaxBytes, "largest enclosure downloaded, 0 for no limit")
+	keepLast := flags.Int("keep", state.Config.DownloadKeepLast, "number of most recent enclosures kept per feed without its own with feed keep, 0 to keep all")
+
+	_, err := command.ParseFlags(flags)
+
+	if err != nil {
+		return err
+	}
+
+	if *dir == "" {
+		homeDir, err := os.UserHomeDir()
+
+		if err != nil {
+			return err
+		}
+
+		*dir = filepath.Join(homeDir, defaultDownloadDir)
+	}
+
+	enclosures, err := state.DbQueries.GetEnclosuresForUser(ctx, user.ID)
+
+	if err != nil {
+		return err
+	}
+
+	// enclosures come newest first within each feed
+	seen := map[uuid.UUID]int{}
+	downloaded := 0
+
+	for _, enclosure := range enclosures {
+		rank := seen[enclosure.FeedID]
+		seen[enclosure.FeedID]++
+
+		keep := *keepLast
+		if enclosure.DownloadKeepLast.Valid {
+			keep = int(enclosure.DownloadKeepLast.Int32)
+		}
+
+		if keep > 0 && rank >= keep {
+			if enclosure.DownloadedAt.Valid {
+				err = removeDownload(ctx, state, user, enclosure)
+
+				if err != nil {
+					slog.WarnContext(ctx, "failed to remove download", "path", enclosure.FilePath, "error", err)
+				}
+			}
+			continue
+		}
+
+		if enclosure.DownloadedAt.Valid && fileExists(enclosure.FilePath) {
+			continue
+		}
+
+		if *maxBytes > 0 && enclosure.Length > *maxBytes {
+			fmt.Printf("Skipping %s: %s is over the size limit\n", enclosure.Url, formatBytes(enclosure.Length))
+			continue
+		}
+
+		filePath := enclosurePath(*dir, enclosure)
+
+		fmt.Printf("Downloading %s\n", enclosure.Url)
+
+		err = downloadFile(ctx, state.HTTPClient, enclosure.Url, filePath, *maxBytes)
+
+		if err != nil {
+			slog.WarnContext(ctx, "failed to download enclosure", "url", enclosure.Url, "feed_id", enclosure.FeedID, "user", user.Name, "error", err)
+			continue
+		}
+
+		err = state.DbQueries.MarkEnclosureDownloaded(ctx, database.MarkEnclosureDownloadedParams{
+			UserID:       user.ID,
+			EnclosureID:  enclosure.ID,
+			FilePath:     filePath,
+			DownloadedAt: time.Now(),
+		})
+
+		if err != nil {
+			return err
+		}
+
+		downloaded++
+	}
+
+	fmt.Printf("Downloaded %d enclosures to %s\n", downloaded, *dir)
+
+	return nil
+}
+
+// feedKeepHandler sets how many enclosures of a feed download keeps for the
+// current user, overriding --keep.
+func feedKeepHandler(ctx context.Context, state *config.State, command config.Command, user database.User) error {
+	if len(command.Args) < 2 {
+		return errors.New("please specify the url of the feed and the number of enclosures to keep, 0 for all or default for --keep")
+	}
+
+	feed, err := state.DbQueries.FeedFromUrl(ctx, command.Args[0])
+
+	if err != nil {
+		return fmt.Errorf("failed to find feed %s: %w", command.Args[0], err)
+	}
+
+	var keepLast sql.NullInt32
+
+	if command.Args[1] != "default" {
+		n, err := strconv.Atoi(command.Args[1])
+
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid number of enclosures to keep %q", command.Args[1])
+		}
+
+		keepLast = sql.NullInt32{Int32: int32(n), Valid: true}
+	}
+
+	rows, err := state.DbQueries.SetFeedFollowKeepLast(ctx, database.SetFeedFollowKeepLastParams{
+		FeedID:           feed.ID,
+		UserID:           user.ID,
+		DownloadKeepLast: keepLast,
+		UpdatedAt:        time.Now(),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("you do not follow %s", feed.Url)
+	}
+
+	switch {
+	case !keepLast.Valid:
+		fmt.Printf("download keeps the --keep default for %s\n", feed.Url)
+	case keepLast.Int32 == 0:
+		fmt.Printf("download keeps every enclosure of %s\n", feed.Url)
+	default:
+		fmt.Printf("download keeps the last %d enclosures of %s\n", keepLast.Int32, feed.Url)
+	}
+
+	return nil
+}
+
+// removeDownload forgets that user downloaded enclosure, and removes the file
+// unless another user downloaded it to the same path.
+func removeDownload(ctx context.Context, state *config.State, user database.User, enclosure database.GetEnclosuresForUserRow) error {
+	others, err := state.DbQueries.CountOtherDownloadsOfFile(ctx, database.CountOtherDownloadsOfFileParams{
+		FilePath: enclosure.FilePath,
+		UserID:   user.ID,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if others == 0 {
+		err = os.Remove(enclosure.FilePath)
+
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	return state.DbQueries.ClearEnclosureDownload(ctx, database.ClearEnclosureDownloadParams{
+		UserID:      user.ID,
+		EnclosureID: enclosure.ID,
+	})
+}
+
+// errRangeMismatch is returned when a server answers a range request with a
+// range other than the one asked for.
+var errRangeMismatch = errors.New("server sent another range than requested")
+
+// downloadFile downloads into a .part file next to filePath and resumes from
+// it with a range request when a previous download was interrupted. The
+// range is only honoured while the enclosure is unchanged since the .part
+// file was started, which is checked with If-Range against the ETag or
+// Last-Modified saved next to it.
+func downloadFile(ctx context.Context, client *http.Client, fileURL string, filePath string, maxBytes int64) error {
+	partPath := filePath + ".part"
+
+	err := os.MkdirAll(filepath.Dir(filePath), 0755)
+
+	if err != nil {
+		return err
+	}
+
+	var offset int64
+
+	validator, err := os.ReadFile(partPath + ".validator")
+	if err == nil {
+		info, err := os.Stat(partPath)
+		if err == nil {
+			offset = info.Size()
+		}
+	}
+
+	err = downloadPart(ctx, client, fileURL, partPath, offset, string(validator), maxBytes)
+
+	if errors.Is(err, errRangeMismatch) {
+		err = downloadPart(ctx, client, fileURL, partPath, 0, "", maxBytes)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	os.Remove(partPath + ".validator")
+
+	return os.Rename(partPath, filePath)
+}
+
+// downloadPart writes fileURL to partPath, appending from offset when the
+// server still has the representation validator names.
+func downloadPart(ctx context.Context, client *http.Client, fileURL string, partPath string, offset int64, validator string, maxBytes int64) error {
+	// enclosures can take far longer to download than the client timeout
+	// allows for feeds, so it bounds how long the body may go without a
+	// byte coming in instead
+	downloadClient := *client
+	downloadClient.Timeout = 0
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	idle := newIdleTimer(client.Timeout, func() {
+		cancel(errDownloadStalled)
+	})
+	defer idle.stop()
+
+	err := downloadBody(ctx, &downloadClient, fileURL, partPath, offset, validator, maxBytes, idle)
+
+	if err != nil && errors.Is(context.Cause(ctx), errDownloadStalled) {
+		return fmt.Errorf("%w: nothing received for %s", errDownloadStalled, client.Timeout)
+	}
+
+	return err
+}
+
+func downloadBody(ctx context.Context, client *http.Client, fileURL string, partPath string, offset int64, validator string, maxBytes int64, idle *idleTimer) error {
+	request, err := http.NewRequestWithContext(ctx, "GET", fileURL, nil)
+
+	if err != nil {
+		return err
+	}
+
+	if offset > 0 {
+		request.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		request.Header.Set("If-Range", validator)
+	}
+
+	response, err := client.Do(request)
+
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+
+	openFlags := os.O_CREATE | os.O_WRONLY
+
+	switch response.StatusCode {
+	case http.StatusPartialContent:
+		start, ok := contentRangeStart(response.Header.Get("Content-Range"))
+
+		if offset == 0 || !ok || start != offset {
+			return errRangeMismatch
+		}
+
+		openFlags |= os.O_APPEND
+	case http.StatusOK:
+		// the server ignored the range or the enclosure changed, start over
+		offset = 0
+		openFlags |= os.O_TRUNC
+	case http.StatusRequestedRangeNotSatisfiable:
+		// the part file already holds the whole enclosure
+		if offset > 0 {
+			return nil
+		}
+		return fmt.Errorf("status code: %d", response.StatusCode)
+	default:
+		return fmt.Errorf("status code: %d", response.StatusCode)
+	}
+
+	if maxBytes > 0 && response.ContentLength > 0 && offset+response.ContentLength > maxBytes {
+		return errDownloadTooLarge
+	}
+
+	if offset == 0 {
+		err = saveValidator(partPath+".validator", response.Header)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	file, err := os.OpenFile(partPath, openFlags, 0644)
+
+	if err != nil {
+		return err
+	}
+
+	var body io.Reader = &idleReader{reader: response.Body, idle: idle}
+	if maxBytes > 0 {
+		// read one byte past the limit to tell a body of exactly maxBytes
+		// apart from a larger one
+		body = io.LimitReader(body, maxBytes-offset+1)
+	}
+
+	written, err := io.Copy(file, body)
+
+	closeErr := file.Close()
+
+	if err != nil {
+		return err
+	}
+
+	if closeErr != nil {
+		return closeErr
+	}
+
+	if maxBytes > 0 && offset+written > maxBytes {
+		os.Remove(partPath)
+		os.Remove(partPath + ".validator")
+		return errDownloadTooLarge
+	}
+
+	return nil
+}
+
+// idleTimer calls its func once it is not reset for timeout. A zero timeout
+// never fires.
+type idleTimer struct {
+	timer   *time.Timer
+	timeout time.Duration
+}
+
+func newIdleTimer(timeout time.Duration, f func()) *idleTimer {
+	if timeout <= 0 {
+		return &idleTimer{}
+	}
+
+	return &idleTimer{timer: time.AfterFunc(timeout, f), timeout: timeout}
+}
+
+func (t *idleTimer) reset() {
+	if t.timer != nil {
+		t.timer.Reset(t.timeout)
+	}
+}
+
+func (t *idleTimer) stop() {
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+}
+
+// idleReader resets idle whenever bytes come in.
+type idleReader struct {
+	reader io.Reader
+	idle   *idleTimer
+}
+
+func (r *idleReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+
+	if n > 0 {
+		r.idle.reset()
+	}
+
+	return n, err
+}
+
+// saveValidator saves what If-Range can check a resumed download against: a
+// strong ETag, or else Last-Modified. Without either the download cannot be
+// resumed safely and starts over next time.
+func saveValidator(validatorPath string, header http.Header) error {
+	validator := header.Get("ETag")
+	if validator == "" || strings.HasPrefix(validator, "W/") {
+		validator = header.Get("Last-Modified")
+	}
+
+	if validator == "" {
+		err := os.Remove(validatorPath)
+
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+
+		return nil
+	}
+
+	return os.WriteFile(validatorPath, []byte(validator), 0644)
+}
+
+// contentRangeStart returns the first byte of a "bytes first-last/length"
+// Content-Range.
+func contentRangeStart(contentRange string) (int64, bool) {
+	byteRange, ok := strings.CutPrefix(contentRange, "bytes ")
+
+	if !ok {
+		return 0, false
+	}
+
+	first, _, ok := strings.Cut(byteRange, "-")
+
+	if !ok {
+		return 0, false
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+
+	return start, err == nil
+}
+
+func enclosurePath(dir string, enclosure database.GetEnclosuresForUserRow) string {
+	name := enclosure.ID.String()
+
+	parsedURL, err := url.Parse(enclosure.Url)
+	if err == nil {
+		if base := path.Base(parsedURL.Path); base != "." && base != "/" {
+			name = fmt.Sprintf("%s-%s", enclosure.ID.String()[:8], base)
+		}
+	}
+
+	fileName := fmt.Sprintf("%s-%s", enclosure.PublishedAt.Format("2006-01-02"), sanitizeFileName(name))
+
+	return filepath.Join(dir, sanitizeFileName(enclosure.FeedName), fileName)
+}
+
+func sanitizeFileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < ' ' || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(name))
+
+	if name == "" || name == "." || name == ".." {
+		return "_"
+	}
+
+	return name
+}
+
+func fileExists(filePath string) bool {
+	if filePath == "" {
+		return false
+	}
+
+	_, err := os.Stat(filePath)
+
+	return err == nil
+}
+
+func formatBytes(size int64) string {
+	const unit = 1024
+
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,161 @@
+package agg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDownloadFileResume(t *testing.T) {
+	content := strings.Repeat("0123456789", 10)
+
+	tests := []struct {
+		name      string
+		part      string
+		validator string
+		handler   http.HandlerFunc
+	}{
+		{
+			name: "fresh download",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("ETag", `"v1"`)
+				http.ServeContent(w, r, "", time.Time{}, strings.NewReader(content))
+			},
+		},
+		{
+			name:      "resumed",
+			part:      content[:40],
+			validator: `"v1"`,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Range") != "bytes=40-" || r.Header.Get("If-Range") != `"v1"` {
+					t.Errorf("Range = %q, If-Range = %q", r.Header.Get("Range"), r.Header.Get("If-Range"))
+				}
+
+				w.Header().Set("ETag", `"v1"`)
+				http.ServeContent(w, r, "", time.Time{}, strings.NewReader(content))
+			},
+		},
+		{
+			name:      "changed since the part was started",
+			part:      "stale content of the old file",
+			validator: `"v0"`,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("ETag", `"v1"`)
+				http.ServeContent(w, r, "", time.Time{}, strings.NewReader(content))
+			},
+		},
+		{
+			name: "part without a validator",
+			part: "stale content of the old file",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Range") != "" {
+					t.Errorf("Range = %q without a validator", r.Header.Get("Range"))
+				}
+
+				http.ServeContent(w, r, "", time.Time{}, strings.NewReader(content))
+			},
+		},
+		{
+			name:      "other range sent back",
+			part:      content[:40],
+			validator: `"v1"`,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Range") != "" {
+					w.Header().Set("Content-Range", fmt.Sprintf("bytes 0-%d/%d", len(content)-1, len(content)))
+					w.WriteHeader(http.StatusPartialContent)
+					fmt.Fprint(w, content)
+					return
+				}
+
+				w.Header().Set("ETag", `"v1"`)
+				fmt.Fprint(w, content)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(test.handler)
+			defer server.Close()
+
+			filePath := filepath.Join(t.TempDir(), "episode.mp3")
+
+			if test.part != "" {
+				err := os.WriteFile(filePath+".part", []byte(test.part), 0644)
+
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if test.validator != "" {
+				err := os.WriteFile(filePath+".part.validator", []byte(test.validator), 0644)
+
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := downloadFile(context.Background(), server.Client(), server.URL, filePath, 0)
+
+			if err != nil {
+				t.Fatalf("downloadFile() error = %v", err)
+			}
+
+			got, err := os.ReadFile(filePath)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(got) != content {
+				t.Errorf("downloaded %q, want %q", got, content)
+			}
+
+			if fileExists(filePath+".part") || fileExists(filePath+".part.validator") {
+				t.Error("the part file or its validator was left behind")
+			}
+		})
+	}
+}
+
+func TestDownloadFileStalled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Length", "100")
+		fmt.Fprint(w, strings.Repeat("x", 40))
+		w.(http.Flusher).Flush()
+
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	client := server.Client()
+	client.Timeout = 100 * time.Millisecond
+
+	filePath := filepath.Join(t.TempDir(), "episode.mp3")
+
+	err := downloadFile(context.Background(), client, server.URL, filePath, 0)
+
+	if !errors.Is(err, errDownloadStalled) {
+		t.Fatalf("downloadFile() error = %v, want errDownloadStalled", err)
+	}
+
+	part, err := os.ReadFile(filePath + ".part")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// what came in before the stall is kept to resume from
+	if len(part) != 40 {
+		t.Errorf("part file holds %d bytes, want 40", len(part))
+	}
+}
@@ -0,0 +1,258 @@
+package agg
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/samuelea/gator/internal/database"
+)
+
+// cacheValidators are what a feed's last response told us to send back so
+// the server can answer 304 Not Modified when nothing changed.
+type cacheValidators struct {
+	ETag         string
+	LastModified string
+	ContentHash  string
+}
+
+// statusError is returned for responses that are neither 200 nor 304.
+// RetryAfter is set when the server asked us to come back later.
+type statusError struct {
+	StatusCode int
+	RetryAfter time.Duration
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("status code: %d", e.StatusCode)
+}
+
+// parseError is returned when a response came back but is not a feed we
+// can read.
+type parseError struct {
+	err error
+}
+
+func (e *parseError) Error() string {
+	return e.err.Error()
+}
+
+func (e *parseError) Unwrap() error {
+	return e.err
+}
+
+type redirect struct {
+	StatusCode int
+	URL        string
+}
+
+// maxErrorBodyBytes bounds how much of the body of an error response is read
+// to be kept in the fetch log.
+const maxErrorBodyBytes = 64 << 10
+
+// fetchResult is returned along with the error of a failed fetch whenever a
+// response came back, so the fetch log can record its status and body.
+type fetchResult struct {
+	Feed        *RSSFeed
+	NotModified bool
+	Validators  cacheValidators
+	Redirects   []redirect
+	CacheFor    time.Duration
+	StatusCode  int
+	Bytes       int64
+	Body        []byte
+	// DroppedItems is how many items past the item limit were skipped
+	DroppedItems int
+}
+
+// permanentURL is where the feed now lives when every redirect on the way
+// was permanent. A temporary redirect anywhere in the chain means the
+// original url should be kept.
+func (r *fetchResult) permanentURL() string {
+	permanentURL := ""
+
+	for _, hop := range r.Redirects {
+		if hop.StatusCode != http.StatusMovedPermanently && hop.StatusCode != http.StatusPermanentRedirect {
+			return ""
+		}
+
+		permanentURL = hop.URL
+	}
+
+	return permanentURL
+}
+
+func validatorsFromFeed(feed database.Feed) cacheValidators {
+	return cacheValidators{
+		ETag:         feed.Etag,
+		LastModified: feed.LastModified,
+		ContentHash:  feed.ContentHash,
+	}
+}
+
+func fetchFeed(ctx context.Context, client *http.Client, feedURL string, validators cacheValidators, auth *feedAuth, limits feedLimits) (*fetchResult, error) {
+	request, err := http.NewRequestWithContext(ctx, "GET", feedURL, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/feed+json, application/xml;q=0.9, */*;q=0.8")
+	request.Header.Set("Accept-Encoding", acceptEncoding)
+
+	auth.apply(request)
+
+	if validators.ETag != "" {
+		request.Header.Set("If-None-Match", validators.ETag)
+	}
+	if validators.LastModified != "" {
+		request.Header.Set("If-Modified-Since", validators.LastModified)
+	}
+
+	var redirects []redirect
+
+	httpClient := *client
+	httpClient.CheckRedirect = func(request *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+
+		redirects = append(redirects, redirect{
+			StatusCode: request.Response.StatusCode,
+			URL:        request.URL.String(),
+		})
+
+		// net/http drops Authorization and Cookie on the way to another
+		// domain, but not the custom headers of a feed, which hold api keys
+		if auth != nil && request.URL.Host != via[0].URL.Host {
+			for name := range auth.Headers {
+				request.Header.Del(name)
+			}
+		}
+
+		return nil
+	}
+
+	response, err := httpClient.Do(request)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotModified {
+		return &fetchResult{
+			NotModified: true,
+			Validators:  validators,
+			Redirects:   redirects,
+			CacheFor:    cacheLifetime(response.Header, time.Now()),
+			StatusCode:  response.StatusCode,
+		}, nil
+	}
+
+	wire := &countingReader{r: response.Body}
+
+	if response.StatusCode != http.StatusOK {
+		var body []byte
+
+		decoded, err := decompress(wire, response.Header.Get("Content-Encoding"))
+		if err == nil {
+			body, _ = io.ReadAll(io.LimitReader(decoded, maxErrorBodyBytes))
+		}
+
+		return &fetchResult{
+			StatusCode: response.StatusCode,
+			Bytes:      wire.n,
+			Body:       body,
+		}, &statusError{
+			StatusCode: response.StatusCode,
+			RetryAfter: parseRetryAfter(response.Header.Get("Retry-After"), time.Now()),
+		}
+	}
+
+	result := &fetchResult{
+		Validators: cacheValidators{
+			ETag:         response.Header.Get("ETag"),
+			LastModified: response.Header.Get("Last-Modified"),
+		},
+		Redirects:  redirects,
+		CacheFor:   cacheLifetime(response.Header, time.Now()),
+		StatusCode: response.StatusCode,
+	}
+
+	decoded, err := decompress(wire, response.Header.Get("Content-Encoding"))
+
+	if err != nil {
+		result.Bytes = wire.n
+		return result, err
+	}
+
+	// servers that ignore conditional requests still send the same bytes
+	// back, so hash the body to avoid saving an unchanged feed again. The
+	// start of the body is kept for the fetch log in case it fails to parse.
+	hash := sha256.New()
+	start := &cappedBuffer{max: maxErrorBodyBytes}
+	body := io.TeeReader(newMaxBytesReader(decoded, limits.MaxBytes), io.MultiWriter(hash, start))
+
+	feed, dropped, err := decodeFeed(body, response.Header.Get("Content-Type"), feedURL, limits.MaxItems)
+
+	// read what follows the document too, so it is part of the hash and a
+	// body cut short after the document is noticed
+	if err == nil {
+		_, err = io.Copy(io.Discard, body)
+	}
+
+	result.Bytes = wire.n
+
+	if err != nil {
+		result.Body = start.data
+		return result, bodyError(err, wire, response.ContentLength)
+	}
+
+	result.Validators.ContentHash = hex.EncodeToString(hash.Sum(nil))
+
+	if result.Validators.ContentHash == validators.ContentHash {
+		result.NotModified = true
+		return result, nil
+	}
+
+	result.DroppedItems = dropped
+
+	cleanUpRSS(feed)
+
+	result.Feed = feed
+
+	return result, nil
+}
+
+// parseRetryAfter reads a Retry-After header, which is either a number of
+// seconds or an http date.
+func parseRetryAfter(header string, now time.Time) time.Duration {
+	header = strings.TrimSpace(header)
+
+	if header == "" {
+		return 0
+	}
+
+	seconds, err := strconv.Atoi(header)
+
+	if err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+
+	date, err := http.ParseTime(header)
+
+	if err != nil {
+		return 0
+	}
+
+	return max(date.Sub(now), 0)
+}
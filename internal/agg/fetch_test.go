@@ -0,0 +1,99 @@
+package agg
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFetchFeedConditional(t *testing.T) {
+	const etag = `"v1"`
+	const lastModified = "Sat, 17 Oct 2026 08:00:00 GMT"
+
+	tests := []struct {
+		name string
+		// honours tells whether the server answers conditional requests
+		// with a 304 or ignores them
+		honours bool
+		body    string
+	}{
+		{name: "304", honours: true, body: testFeed},
+		{name: "same body", honours: false, body: testFeed},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got http.Header
+			body := test.body
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.Header.Clone()
+
+				if test.honours && r.Header.Get("If-None-Match") == etag {
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+
+				w.Header().Set("ETag", etag)
+				w.Header().Set("Last-Modified", lastModified)
+				io.WriteString(w, body)
+			}))
+			defer server.Close()
+
+			first, err := fetchFeed(context.Background(), server.Client(), server.URL, cacheValidators{}, nil, feedLimits{})
+
+			if err != nil {
+				t.Fatalf("fetchFeed() error = %v", err)
+			}
+
+			if got.Get("If-None-Match") != "" || got.Get("If-Modified-Since") != "" {
+				t.Errorf("conditional headers sent without validators: %v", got)
+			}
+
+			if first.NotModified || first.Feed == nil {
+				t.Fatal("first fetch is not modified")
+			}
+
+			if first.Validators.ETag != etag || first.Validators.LastModified != lastModified || first.Validators.ContentHash == "" {
+				t.Errorf("validators = %+v", first.Validators)
+			}
+
+			second, err := fetchFeed(context.Background(), server.Client(), server.URL, first.Validators, nil, feedLimits{})
+
+			if err != nil {
+				t.Fatalf("fetchFeed() error = %v", err)
+			}
+
+			if got.Get("If-None-Match") != etag || got.Get("If-Modified-Since") != lastModified {
+				t.Errorf("If-None-Match = %q, If-Modified-Since = %q", got.Get("If-None-Match"), got.Get("If-Modified-Since"))
+			}
+
+			if !second.NotModified || second.Feed != nil {
+				t.Errorf("second fetch NotModified = %v, want true", second.NotModified)
+			}
+
+			if second.Validators != first.Validators {
+				t.Errorf("validators = %+v, want %+v", second.Validators, first.Validators)
+			}
+
+			if test.honours {
+				return
+			}
+
+			// a new body is saved even when the server keeps its validators
+			body = testFeed + "\n"
+
+			third, err := fetchFeed(context.Background(), server.Client(), server.URL, first.Validators, nil, feedLimits{})
+
+			if err != nil {
+				t.Fatalf("fetchFeed() error = %v", err)
+			}
+
+			if third.NotModified || third.Feed == nil {
+				t.Error("changed body is not modified")
+			}
+		})
+	}
+}
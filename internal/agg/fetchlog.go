@@ -0,0 +1,134 @@
+package agg
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/samuelea/gator/internal/config"
+	"github.com/samuelea/gator/internal/database"
+)
+
+// fetchAttempt is what scrapeFeed learnt about one fetch of a feed, for the
+// fetch log.
+type fetchAttempt struct {
+	FeedID       uuid.UUID
+	StartedAt    time.Time
+	StatusCode   int
+	Bytes        int64
+	ItemsSeen    int
+	ItemsNew     int
+	ItemsDropped int
+	Body         []byte
+	// Rescheduled is set once the next fetch or retry of the feed is
+	// written, after which its lease can be released even if the fetch
+	// failed
+	Rescheduled bool
+}
+
+func recordFetch(ctx context.Context, state *config.State, attempt *fetchAttempt, fetchErr error, opts aggOptions) error {
+	errorText := ""
+	var body []byte
+
+	if fetchErr != nil {
+		errorText = fetchErr.Error()
+
+		if opts.FetchLogBodies {
+			body = attempt.Body
+		}
+	}
+
+	err := state.DbQueries.CreateFetchLog(ctx, database.CreateFetchLogParams{
+		ID:           uuid.New(),
+		FeedID:       attempt.FeedID,
+		StartedAt:    attempt.StartedAt,
+		DurationMs:   int32(time.Since(attempt.StartedAt) / time.Millisecond),
+		HttpStatus:   int32(attempt.StatusCode),
+		Bytes:        attempt.Bytes,
+		ItemsSeen:    int32(attempt.ItemsSeen),
+		ItemsNew:     int32(attempt.ItemsNew),
+		ItemsDropped: int32(attempt.ItemsDropped),
+		Error:        errorText,
+		Body:         body,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return state.DbQueries.TrimFetchLog(ctx, database.TrimFetchLogParams{
+		FeedID: attempt.FeedID,
+		Limit:  int32(opts.FetchLogKept),
+	})
+}
+
+func FetchLogHandler(ctx context.Context, state *config.State, command config.Command) error {
+	flags := flag.NewFlagSet("fetchlog", flag.ContinueOnError)
+	failed := flags.Bool("failed", false, "only show failed fetches")
+	limit := flags.Int("limit", 20, "number of fetches to show")
+	showBody := flags.Bool("body", false, "show the response body kept for failed fetches")
+
+	args, err := command.ParseFlags(flags)
+
+	if err != nil {
+		return err
+	}
+
+	params := database.GetFetchLogParams{
+		FailedOnly: *failed,
+		RowLimit:   int32(*limit),
+	}
+
+	if len(args) >= 1 {
+		feed, err := state.DbQueries.FeedFromUrl(ctx, args[0])
+
+		if err != nil {
+			return fmt.Errorf("failed to find feed %s: %w", args[0], err)
+		}
+
+		params.FeedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+	}
+
+	entries, err := state.DbQueries.GetFetchLog(ctx, params)
+
+	if err != nil {
+		return err
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("No fetches recorded")
+		return nil
+	}
+
+	for _, entry := range entries {
+		status := "no response"
+		if entry.HttpStatus != 0 {
+			status = fmt.Sprintf("HTTP %d", entry.HttpStatus)
+		}
+
+		fmt.Printf("%s %s (%s)\n", entry.StartedAt.Format(time.RFC1123), entry.FeedName, entry.FeedUrl)
+		fmt.Printf("  %s in %v, %s, %d items seen, %d new\n",
+			status,
+			time.Duration(entry.DurationMs)*time.Millisecond,
+			formatBytes(entry.Bytes),
+			entry.ItemsSeen,
+			entry.ItemsNew,
+		)
+
+		if entry.ItemsDropped > 0 {
+			fmt.Printf("  %d items past the item limit were skipped\n", entry.ItemsDropped)
+		}
+
+		if entry.Error != "" {
+			fmt.Printf("  error: %s\n", entry.Error)
+		}
+
+		if *showBody && len(entry.Body) > 0 {
+			fmt.Printf("  body:\n%s\n", entry.Body)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package agg
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/samuelea/gator/internal/config"
+	"github.com/samuelea/gator/internal/database"
+)
+
+const (
+	minRetryDelay = time.Minute
+	maxRetryDelay = 24 * time.Hour
+	// feedErrorsKept is how many errors are kept per feed for feeds --health
+	feedErrorsKept = 20
+)
+
+// retryDelay doubles the delay before retrying a feed on each consecutive
+// failure. A Retry-After sent with a 429 or 503 wins over the backoff.
+func retryDelay(failures int32, err error) time.Duration {
+	var statusErr *statusError
+
+	if errors.As(err, &statusErr) && statusErr.RetryAfter > 0 {
+		if statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode == http.StatusServiceUnavailable {
+			return min(statusErr.RetryAfter, maxRetryDelay)
+		}
+	}
+
+	delay := minRetryDelay
+
+	for i := int32(1); i < failures && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, maxRetryDelay)
+}
+
+func recordFeedFailure(ctx context.Context, state *config.State, feed database.Feed, fetchErr error) error {
+	now := time.Now()
+
+	err := state.DbQueries.RecordFeedFailure(ctx, database.RecordFeedFailureParams{
+		ID:            feed.ID,
+		LastFetchedAt: sql.NullTime{Time: now, Valid: true},
+		LastError:     fetchErr.Error(),
+		NextRetryAt:   sql.NullTime{Time: now.Add(retryDelay(feed.ConsecutiveFailures+1, fetchErr)), Valid: true},
+	})
+
+	if err != nil {
+		return err
+	}
+
+	err = state.DbQueries.CreateFeedError(ctx, database.CreateFeedErrorParams{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		FeedID:    feed.ID,
+		Error:     fetchErr.Error(),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return state.DbQueries.TrimFeedErrors(ctx, database.TrimFeedErrorsParams{
+		FeedID: feed.ID,
+		Limit:  feedErrorsKept,
+	})
+}
+
+func printFeedHealth(ctx context.Context, state *config.State) error {
+	feeds, err := state.DbQueries.GetFailingFeeds(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	if len(feeds) == 0 {
+		fmt.Println("All feeds are healthy")
+		return nil
+	}
+
+	for _, feed := range feeds {
+		fmt.Printf("Feed: %v\n", feed.Name)
+		fmt.Printf("url: %v\n", feed.Url)
+		fmt.Printf("consecutive failures: %d\n", feed.ConsecutiveFailures)
+		fmt.Printf("last success: %s\n", formatNullTime(feed.LastSuccessAt))
+		fmt.Printf("next retry: %s\n", formatNullTime(feed.NextRetryAt))
+
+		feedErrors, err := state.DbQueries.GetFeedErrors(ctx, database.GetFeedErrorsParams{
+			FeedID: feed.ID,
+			Limit:  5,
+		})
+
+		if err != nil {
+			return err
+		}
+
+		for _, feedError := range feedErrors {
+			fmt.Printf("  %s: %s\n", feedError.CreatedAt.Format(time.RFC1123), feedError.Error)
+		}
+	}
+
+	return nil
+}
+
+func formatNullTime(t sql.NullTime) string {
+	if !t.Valid {
+		return "never"
+	}
+
+	return t.Time.Format(time.RFC1123)
+}
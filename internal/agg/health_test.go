@@ -0,0 +1,98 @@
+package agg
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int32
+		err      error
+		want     time.Duration
+	}{
+		{name: "first failure", failures: 1, err: errors.New("boom"), want: time.Minute},
+		{name: "second failure", failures: 2, err: errors.New("boom"), want: 2 * time.Minute},
+		{name: "fifth failure", failures: 5, err: errors.New("boom"), want: 16 * time.Minute},
+		{name: "capped", failures: 12, err: errors.New("boom"), want: maxRetryDelay},
+		{name: "far past the cap", failures: 1000, err: errors.New("boom"), want: maxRetryDelay},
+		{name: "no failures yet", failures: 0, err: errors.New("boom"), want: time.Minute},
+		{
+			name:     "retry after a 429",
+			failures: 5,
+			err:      &statusError{StatusCode: http.StatusTooManyRequests, RetryAfter: 30 * time.Second},
+			want:     30 * time.Second,
+		},
+		{
+			name:     "retry after a 503",
+			failures: 1,
+			err:      &statusError{StatusCode: http.StatusServiceUnavailable, RetryAfter: 3 * time.Hour},
+			want:     3 * time.Hour,
+		},
+		{
+			name:     "retry after is capped",
+			failures: 1,
+			err:      &statusError{StatusCode: http.StatusServiceUnavailable, RetryAfter: 7 * 24 * time.Hour},
+			want:     maxRetryDelay,
+		},
+		{
+			name:     "wrapped",
+			failures: 1,
+			err:      fmt.Errorf("fetching: %w", &statusError{StatusCode: http.StatusTooManyRequests, RetryAfter: time.Hour}),
+			want:     time.Hour,
+		},
+		// only 429 and 503 are expected to come with a Retry-After worth
+		// following
+		{
+			name:     "retry after a 500",
+			failures: 3,
+			err:      &statusError{StatusCode: http.StatusInternalServerError, RetryAfter: time.Hour},
+			want:     4 * time.Minute,
+		},
+		{
+			name:     "503 without retry after",
+			failures: 3,
+			err:      &statusError{StatusCode: http.StatusServiceUnavailable},
+			want:     4 * time.Minute,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := retryDelay(test.failures, test.err)
+
+			if got != test.want {
+				t.Errorf("retryDelay(%d, %v) = %v, want %v", test.failures, test.err, got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		header string
+		want   time.Duration
+	}{
+		{header: "", want: 0},
+		{header: "120", want: 2 * time.Minute},
+		{header: " 5 ", want: 5 * time.Second},
+		{header: "-5", want: 0},
+		{header: now.Add(time.Hour).Format(http.TimeFormat), want: time.Hour},
+		{header: now.Add(-time.Hour).Format(http.TimeFormat), want: 0},
+		{header: "later", want: 0},
+	}
+
+	for _, test := range tests {
+		got := parseRetryAfter(test.header, now)
+
+		if got != test.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", test.header, got, test.want)
+		}
+	}
+}
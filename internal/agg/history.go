@@ -0,0 +1,221 @@
+package agg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/samuelea/gator/internal/config"
+	"github.com/samuelea/gator/internal/database"
+)
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+type postVersion struct {
+	Title       string
+	Description string
+	Content     string
+	SeenAt      time.Time
+}
+
+func (v postVersion) lines() []string {
+	lines := append([]string{"Title: " + v.Title, ""}, strings.Split(v.Description, "\n")...)
+
+	if v.Content != "" {
+		lines = append(lines, "")
+		lines = append(lines, strings.Split(v.Content, "\n")...)
+	}
+
+	return lines
+}
+
+type diffOp struct {
+	Kind byte
+	Line string
+}
+
+func HistoryHandler(ctx context.Context, state *config.State, command config.Command) error {
+	if len(command.Args) < 1 {
+		return errors.New("please specify the id or url of the post")
+	}
+
+	post, err := findPost(ctx, state, command.Args[0])
+
+	if err != nil {
+		return fmt.Errorf("failed to find post %s: %w", command.Args[0], err)
+	}
+
+	revisions, err := state.DbQueries.GetPostRevisions(ctx, post.ID)
+
+	if err != nil {
+		return err
+	}
+
+	if len(revisions) == 0 {
+		fmt.Printf("No revisions recorded for %s\n", post.Url)
+		return nil
+	}
+
+	// a revision holds the content it replaced, so each version was first
+	// seen when the one before it was replaced
+	versions := []postVersion{}
+	seenAt := post.CreatedAt
+
+	for _, revision := range revisions {
+		versions = append(versions, postVersion{
+			Title:       revision.Title,
+			Description: revision.Description,
+			Content:     revision.Content,
+			SeenAt:      seenAt,
+		})
+		seenAt = revision.CreatedAt
+	}
+
+	versions = append(versions, postVersion{
+		Title:       post.Title,
+		Description: post.Description,
+		Content:     post.Content,
+		SeenAt:      seenAt,
+	})
+
+	fmt.Printf("%d revisions of %s\n", len(revisions), post.Url)
+
+	for i := 1; i < len(versions); i++ {
+		fmt.Print(unifiedDiff(
+			fmt.Sprintf("version %d (%v)", i, versions[i-1].SeenAt.Format(time.RFC1123)),
+			fmt.Sprintf("version %d (%v)", i+1, versions[i].SeenAt.Format(time.RFC1123)),
+			versions[i-1].lines(),
+			versions[i].lines(),
+		))
+	}
+
+	return nil
+}
+
+func findPost(ctx context.Context, state *config.State, idOrUrl string) (database.Post, error) {
+	id, err := uuid.Parse(idOrUrl)
+
+	if err == nil {
+		return state.DbQueries.GetPost(ctx, id)
+	}
+
+	return state.DbQueries.GetLatestPostByUrl(ctx, idOrUrl)
+}
+
+func unifiedDiff(fromLabel string, toLabel string, from []string, to []string) string {
+	ops := diffLines(from, to)
+
+	// line numbers in from and to before each op, for the hunk headers
+	fromPos := make([]int, len(ops)+1)
+	toPos := make([]int, len(ops)+1)
+	var changes []int
+
+	for i, op := range ops {
+		fromPos[i+1] = fromPos[i]
+		toPos[i+1] = toPos[i]
+
+		if op.Kind != '+' {
+			fromPos[i+1]++
+		}
+		if op.Kind != '-' {
+			toPos[i+1]++
+		}
+		if op.Kind != ' ' {
+			changes = append(changes, i)
+		}
+	}
+
+	if len(changes) == 0 {
+		return ""
+	}
+
+	var out strings.Builder
+
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromLabel, toLabel)
+
+	for k := 0; k < len(changes); {
+		start := max(changes[k]-diffContext, 0)
+		end := changes[k]
+
+		// merge changes whose context would overlap into a single hunk
+		for k < len(changes) && changes[k] <= end+2*diffContext+1 {
+			end = changes[k]
+			k++
+		}
+
+		end = min(end+diffContext+1, len(ops))
+
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n",
+			hunkRange(fromPos[start], fromPos[end]-fromPos[start]),
+			hunkRange(toPos[start], toPos[end]-toPos[start]),
+		)
+
+		for _, op := range ops[start:end] {
+			fmt.Fprintf(&out, "%c%s\n", op.Kind, op.Line)
+		}
+	}
+
+	return out.String()
+}
+
+func hunkRange(start int, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	default:
+		return fmt.Sprintf("%d,%d", start+1, count)
+	}
+}
+
+// diffLines computes a line diff from the longest common subsequence of
+// both sides.
+func diffLines(from []string, to []string) []diffOp {
+	lcs := make([][]int, len(from)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(to)+1)
+	}
+
+	for i := len(from) - 1; i >= 0; i-- {
+		for j := len(to) - 1; j >= 0; j-- {
+			if from[i] == to[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+
+	for i < len(from) && j < len(to) {
+		switch {
+		case from[i] == to[j]:
+			ops = append(ops, diffOp{Kind: ' ', Line: from[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{Kind: '-', Line: from[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{Kind: '+', Line: to[j]})
+			j++
+		}
+	}
+
+	for ; i < len(from); i++ {
+		ops = append(ops, diffOp{Kind: '-', Line: from[i]})
+	}
+
+	for ; j < len(to); j++ {
+		ops = append(ops, diffOp{Kind: '+', Line: to[j]})
+	}
+
+	return ops
+}
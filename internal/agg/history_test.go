@@ -0,0 +1,118 @@
+package agg
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		from []string
+		to   []string
+		want string
+	}{
+		{
+			name: "unchanged",
+			from: []string{"a", "b"},
+			to:   []string{"a", "b"},
+			want: "",
+		},
+		{
+			name: "changed line",
+			from: []string{"title", "old summary"},
+			to:   []string{"title", "new summary"},
+			want: `--- before
++++ after
+@@ -1,2 +1,2 @@
+ title
+-old summary
++new summary
+`,
+		},
+		{
+			name: "from empty",
+			from: nil,
+			to:   []string{"first"},
+			want: `--- before
++++ after
+@@ -0,0 +1 @@
++first
+`,
+		},
+		{
+			name: "to empty",
+			from: []string{"gone", "too"},
+			to:   nil,
+			want: `--- before
++++ after
+@@ -1,2 +0,0 @@
+-gone
+-too
+`,
+		},
+		{
+			name: "context is trimmed",
+			from: []string{"1", "2", "3", "4", "5", "6", "7", "8"},
+			to:   []string{"1", "2", "3", "4", "5", "6", "7", "eight"},
+			want: `--- before
++++ after
+@@ -5,4 +5,4 @@
+ 5
+ 6
+ 7
+-8
++eight
+`,
+		},
+		{
+			name: "distant changes get their own hunks",
+			from: strings.Split("a b c d e f g h i j k l", " "),
+			to:   strings.Split("A b c d e f g h i j k L", " "),
+			want: `--- before
++++ after
+@@ -1,4 +1,4 @@
+-a
++A
+ b
+ c
+ d
+@@ -9,4 +9,4 @@
+ i
+ j
+ k
+-l
++L
+`,
+		},
+		{
+			name: "close changes share a hunk",
+			from: strings.Split("a b c d e f g h", " "),
+			to:   strings.Split("A b c d e f g H", " "),
+			want: `--- before
++++ after
+@@ -1,8 +1,8 @@
+-a
++A
+ b
+ c
+ d
+ e
+ f
+ g
+-h
++H
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := unifiedDiff("before", "after", test.from, test.to)
+
+			if got != test.want {
+				t.Errorf("unifiedDiff() =\n%s\nwant\n%s", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package agg
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"strconv"
+	"strings"
+)
+
+// JSONFeed covers versions 1.0 and 1.1 of https://jsonfeed.org. Version 1.0
+// has a single author object which 1.1 replaced with an authors list, on
+// the feed as well as on its items.
+type JSONFeed struct {
+	Version     string           `json:"version"`
+	Title       string           `json:"title"`
+	HomePageURL string           `json:"home_page_url"`
+	FeedURL     string           `json:"feed_url"`
+	Description string           `json:"description"`
+	Author      *JSONFeedAuthor  `json:"author"`
+	Authors     []JSONFeedAuthor `json:"authors"`
+	Items       []JSONFeedItem   `json:"items"`
+}
+
+type JSONFeedItem struct {
+	ID            json.RawMessage      `json:"id"`
+	URL           string               `json:"url"`
+	ExternalURL   string               `json:"external_url"`
+	Title         string               `json:"title"`
+	ContentHTML   string               `json:"content_html"`
+	ContentText   string               `json:"content_text"`
+	Summary       string               `json:"summary"`
+	DatePublished string               `json:"date_published"`
+	DateModified  string               `json:"date_modified"`
+	Author        *JSONFeedAuthor      `json:"author"`
+	Authors       []JSONFeedAuthor     `json:"authors"`
+	Attachments   []JSONFeedAttachment `json:"attachments"`
+}
+
+type JSONFeedAuthor struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+type JSONFeedAttachment struct {
+	URL         string `json:"url"`
+	MimeType    string `json:"mime_type"`
+	Title       string `json:"title"`
+	SizeInBytes int64  `json:"size_in_bytes"`
+}
+
+// isJSONFeed sniffs the content type first and falls back to the body since
+// plenty of servers still send json feeds as text/plain or application/json.
+func isJSONFeed(contentType string, body []byte) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+
+	if err == nil && (mediaType == "application/feed+json" || mediaType == "application/json") {
+		return true
+	}
+
+	return bytes.HasPrefix(bytes.TrimSpace(body), []byte("{"))
+}
+
+func jsonFeedToRSS(jsonFeed *JSONFeed, feedURL string) *RSSFeed {
+	var feed RSSFeed
+
+	feed.Channel.Title = strings.TrimSpace(jsonFeed.Title)
+	feed.Channel.Link = resolveLink(feedURL, jsonFeed.HomePageURL)
+	feed.Channel.Description = strings.TrimSpace(jsonFeed.Description)
+
+	for _, item := range jsonFeed.Items {
+		link := item.URL
+		if link == "" {
+			link = item.ExternalURL
+		}
+
+		description := item.Summary
+		if description == "" {
+			description = item.ContentHTML
+		}
+		if description == "" {
+			description = item.ContentText
+		}
+
+		// titles are optional for microblog style feeds
+		title := item.Title
+		if title == "" {
+			title = item.Summary
+		}
+
+		content := item.ContentHTML
+		if content == "" {
+			content = item.ContentText
+		}
+
+		pubDate := item.DatePublished
+		if pubDate == "" {
+			pubDate = item.DateModified
+		}
+
+		var enclosures []RSSEnclosure
+
+		for _, attachment := range item.Attachments {
+			enclosures = append(enclosures, RSSEnclosure{
+				URL:    resolveLink(feedURL, strings.TrimSpace(attachment.URL)),
+				Length: strconv.FormatInt(attachment.SizeInBytes, 10),
+				Type:   attachment.MimeType,
+			})
+		}
+
+		feed.Channel.Item = append(feed.Channel.Item, RSSItem{
+			Title:       strings.TrimSpace(title),
+			Link:        resolveLink(feedURL, strings.TrimSpace(link)),
+			Description: strings.TrimSpace(description),
+			PubDate:     strings.TrimSpace(pubDate),
+			GUID:        item.id(),
+			Enclosures:  enclosures,
+			Content:     content,
+			Creators:    item.authorNames(jsonFeed),
+		})
+	}
+
+	return &feed
+}
+
+// id returns the item id as a string. The spec requires a string but numeric
+// ids are common enough in the wild to be worth accepting.
+func (item JSONFeedItem) id() string {
+	var id string
+
+	err := json.Unmarshal(item.ID, &id)
+
+	if err == nil {
+		return strings.TrimSpace(id)
+	}
+
+	return strings.TrimSpace(string(item.ID))
+}
+
+// authorNames returns the names of the item authors, or of the single
+// author object json feed 1.0 used instead of the authors list. Items
+// without authors have those of the feed.
+func (item JSONFeedItem) authorNames(jsonFeed *JSONFeed) []string {
+	authors := item.Authors
+	if len(authors) == 0 && item.Author != nil {
+		authors = []JSONFeedAuthor{*item.Author}
+	}
+	if len(authors) == 0 {
+		authors = jsonFeed.Authors
+	}
+	if len(authors) == 0 && jsonFeed.Author != nil {
+		authors = []JSONFeedAuthor{*jsonFeed.Author}
+	}
+
+	var names []string
+
+	for _, author := range authors {
+		if author.Name != "" {
+			names = append(names, author.Name)
+		}
+	}
+
+	return names
+}
@@ -0,0 +1,134 @@
+package agg
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseJSONFeed(t *testing.T) {
+	microblog := []RSSItem{
+		{
+			Title:       "Short post without a title",
+			Link:        "https://example.org/2026/10/1024",
+			Description: "Short post without a title",
+			PubDate:     "2026-10-10T12:00:00Z",
+			GUID:        "1024",
+			Content:     "Short post without a title",
+			Creators:    []string{"Ada", "Grace"},
+		},
+		{
+			Title:       "Episode 7",
+			Link:        "https://example.org/episodes/7",
+			Description: "<p>Show notes</p>",
+			PubDate:     "2026-10-11T08:30:00+02:00",
+			GUID:        "episode-7",
+			Enclosures: []RSSEnclosure{
+				{URL: "https://example.org/media/episode-7.mp3", Length: "48213000", Type: "audio/mpeg"},
+				{URL: "https://cdn.example.org/episode-7.m4a", Length: "0", Type: "audio/mp4"},
+			},
+			Content:  "<p>Show notes</p>",
+			Creators: []string{"Linus"},
+		},
+		{
+			Title:       "Linked elsewhere",
+			Link:        "https://elsewhere.example.com/article",
+			Description: "A link",
+			GUID:        "3.5",
+			Content:     "<p>Read this</p>",
+			Creators:    []string{"Ada", "Grace"},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		file        string
+		contentType string
+		feedURL     string
+		title       string
+		link        string
+		items       []RSSItem
+	}{
+		{
+			name:        "version 1.1",
+			file:        "microblog.json",
+			contentType: "application/feed+json",
+			feedURL:     "https://example.org/feed.json",
+			title:       "Microblog",
+			link:        "https://example.org/",
+			items:       microblog,
+		},
+		{
+			name:        "sniffed from a text/plain body",
+			file:        "microblog.json",
+			contentType: "text/plain; charset=utf-8",
+			feedURL:     "https://example.org/feed.json",
+			title:       "Microblog",
+			link:        "https://example.org/",
+			items:       microblog,
+		},
+		{
+			name:        "version 1.0",
+			file:        "version_1_0.json",
+			contentType: "application/json",
+			feedURL:     "https://example.net/feed.json",
+			title:       "Old style",
+			link:        "https://example.net/",
+			items: []RSSItem{
+				{
+					Title:       "Own author",
+					Link:        "https://example.net/a",
+					Description: "<p>a</p>",
+					PubDate:     "2026-09-01T00:00:00Z",
+					GUID:        "https://example.net/a",
+					Content:     "<p>a</p>",
+					Creators:    []string{"Item Author"},
+				},
+				{
+					Title:       "Feed author",
+					Link:        "https://example.net/b",
+					Description: "b",
+					PubDate:     "2026-09-02T00:00:00Z",
+					GUID:        "https://example.net/b",
+					Content:     "b",
+					Creators:    []string{"Feed Author"},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			body, err := os.ReadFile(filepath.Join("testdata", test.file))
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			feed, err := parseFeed(body, test.contentType, test.feedURL)
+
+			if err != nil {
+				t.Fatalf("parseFeed() error = %v", err)
+			}
+
+			if feed.Channel.Title != test.title {
+				t.Errorf("title = %q, want %q", feed.Channel.Title, test.title)
+			}
+
+			if feed.Channel.Link != test.link {
+				t.Errorf("link = %q, want %q", feed.Channel.Link, test.link)
+			}
+
+			if len(feed.Channel.Item) != len(test.items) {
+				t.Fatalf("got %d items, want %d", len(feed.Channel.Item), len(test.items))
+			}
+
+			for i, item := range feed.Channel.Item {
+				if !reflect.DeepEqual(item, test.items[i]) {
+					t.Errorf("item %d = %+v, want %+v", i, item, test.items[i])
+				}
+			}
+		})
+	}
+}
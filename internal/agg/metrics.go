@@ -0,0 +1,173 @@
+package agg
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/samuelea/gator/internal/config"
+	"github.com/samuelea/gator/internal/database"
+)
+
+var (
+	fetchesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gator_fetches_total",
+		Help: "Feed fetches by HTTP status, or error when no response came back.",
+	}, []string{"status"})
+
+	fetchDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "gator_fetch_duration_seconds",
+		Help:    "Time taken to fetch a feed and save its posts.",
+		Buckets: prometheus.ExponentialBuckets(0.05, 2, 12),
+	})
+
+	bytesDownloaded = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "gator_fetch_bytes_total",
+		Help: "Bytes of feed bodies downloaded.",
+	})
+
+	newPostsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gator_new_posts_total",
+		Help: "New posts saved, by feed id.",
+	}, []string{"feed_id"})
+
+	parseErrorsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "gator_parse_errors_total",
+		Help: "Feeds that could not be parsed.",
+	})
+
+	dueFeeds = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "gator_due_feeds",
+		Help: "Feeds due for a fetch at the start of the last run.",
+	})
+
+	lastRunTimestamp = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "gator_last_run_timestamp_seconds",
+		Help: "When the last run over the due feeds finished.",
+	})
+
+	dbQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "gator_db_query_duration_seconds",
+		Help:    "Time taken by database queries, by query name.",
+		Buckets: prometheus.ExponentialBuckets(0.0005, 2, 14),
+	}, []string{"query"})
+)
+
+// serveMetrics serves /metrics on addr until ctx is done. The listener is
+// opened before returning so a bad address fails agg right away.
+func serveMetrics(ctx context.Context, addr string) error {
+	listener, err := net.Listen("tcp", addr)
+
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	context.AfterFunc(ctx, func() {
+		server.Close()
+	})
+
+	go func() {
+		err := server.Serve(listener)
+
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("metrics server stopped", "error", err)
+		}
+	}()
+
+	slog.Info("serving metrics", "addr", listener.Addr().String())
+
+	return nil
+}
+
+func observeFetch(attempt *fetchAttempt, err error) {
+	status := "error"
+	if attempt.StatusCode != 0 {
+		status = strconv.Itoa(attempt.StatusCode)
+	}
+
+	fetchesTotal.WithLabelValues(status).Inc()
+	fetchDuration.Observe(time.Since(attempt.StartedAt).Seconds())
+	bytesDownloaded.Add(float64(attempt.Bytes))
+
+	if attempt.ItemsNew > 0 {
+		newPostsTotal.WithLabelValues(attempt.FeedID.String()).Add(float64(attempt.ItemsNew))
+	}
+
+	var parseErr *parseError
+
+	if errors.As(err, &parseErr) {
+		parseErrorsTotal.Inc()
+	}
+}
+
+// timedDB times every query sent through it, naming queries after the
+// "-- name:" comment sqlc puts at the top of each one.
+type timedDB struct {
+	db database.DBTX
+}
+
+// queriesTimed is set by agg when it serves metrics, so the queries of
+// transactions are timed like the others.
+var queriesTimed bool
+
+// txQueries returns the queries of tx, timed when agg serves metrics.
+func txQueries(state *config.State, tx *sql.Tx) *database.Queries {
+	if queriesTimed {
+		return database.New(timedDB{db: tx})
+	}
+
+	return state.DbQueries.WithTx(tx)
+}
+
+func (t timedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	defer t.observe(query, time.Now())
+	return t.db.ExecContext(ctx, query, args...)
+}
+
+func (t timedDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return t.db.PrepareContext(ctx, query)
+}
+
+func (t timedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	defer t.observe(query, time.Now())
+	return t.db.QueryContext(ctx, query, args...)
+}
+
+func (t timedDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	defer t.observe(query, time.Now())
+	return t.db.QueryRowContext(ctx, query, args...)
+}
+
+func (t timedDB) observe(query string, start time.Time) {
+	dbQueryDuration.WithLabelValues(queryName(query)).Observe(time.Since(start).Seconds())
+}
+
+func queryName(query string) string {
+	name, ok := strings.CutPrefix(query, "-- name: ")
+
+	if !ok {
+		return "unknown"
+	}
+
+	name, _, _ = strings.Cut(name, " ")
+
+	return name
+}
@@ -0,0 +1,202 @@
+package agg
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/samuelea/gator/internal/config"
+	"github.com/samuelea/gator/internal/database"
+)
+
+// moveFeed points a feed at the url it was permanently redirected to. When
+// another feed already uses that url, the follows and posts of the moved
+// feed are merged into it and the moved feed is deleted. It returns the feed
+// the moved one now is.
+func moveFeed(ctx context.Context, state *config.State, feed database.Feed, newURL string) (database.Feed, error) {
+	now := time.Now()
+
+	tx, err := state.Db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return feed, err
+	}
+
+	defer tx.Rollback()
+
+	queries := txQueries(state, tx)
+
+	existing, err := queries.FeedFromUrl(ctx, newURL)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		err = queries.UpdateFeedUrl(ctx, database.UpdateFeedUrlParams{
+			ID:        feed.ID,
+			Url:       newURL,
+			UpdatedAt: now,
+		})
+
+		if err != nil {
+			return feed, err
+		}
+
+		feedLogger(feed).InfoContext(ctx, "feed moved", "new_url", newURL)
+
+		feed.Url = newURL
+
+		return feed, tx.Commit()
+	}
+
+	if err != nil {
+		return feed, err
+	}
+
+	err = queries.MoveFeedFollows(ctx, database.MoveFeedFollowsParams{
+		CreatedAt:  now,
+		ToFeedID:   existing.ID,
+		FromFeedID: feed.ID,
+	})
+
+	if err != nil {
+		return feed, err
+	}
+
+	err = queries.MovePosts(ctx, database.MovePostsParams{
+		ToFeedID:   existing.ID,
+		FromFeedID: feed.ID,
+	})
+
+	if err != nil {
+		return feed, err
+	}
+
+	err = moveFeedAuth(ctx, state, queries, feed.ID, existing.ID)
+
+	if err != nil {
+		return feed, err
+	}
+
+	err = queries.DeleteFeed(ctx, feed.ID)
+
+	if err != nil {
+		return feed, err
+	}
+
+	feedLogger(feed).InfoContext(ctx, "feed moved and merged", "new_url", newURL, "merged_into", existing.ID)
+
+	return existing, tx.Commit()
+}
+
+// moveFeedAuth gives the credentials of a merged feed to the feed it was
+// merged into, unless that feed has its own. They are sealed again since the
+// feed id is bound to them.
+func moveFeedAuth(ctx context.Context, state *config.State, queries *database.Queries, fromID, toID uuid.UUID) error {
+	stored, err := queries.GetFeedAuth(ctx, fromID)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	_, err = queries.GetFeedAuth(ctx, toID)
+
+	if err == nil {
+		return nil
+	}
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	key, err := state.Config.FeedCredentialsKey()
+
+	if err != nil {
+		return err
+	}
+
+	auth, err := openFeedAuth(key, fromID, stored.Credentials)
+
+	if err != nil {
+		return err
+	}
+
+	sealed, err := sealFeedAuth(key, toID, auth)
+
+	if err != nil {
+		return err
+	}
+
+	return queries.UpsertFeedAuth(ctx, database.UpsertFeedAuthParams{
+		FeedID:      toID,
+		CreatedAt:   stored.CreatedAt,
+		UpdatedAt:   time.Now(),
+		Credentials: sealed,
+	})
+}
+
+// disableGoneFeed stops fetching a feed its publisher removed and tells its
+// followers about it.
+func disableGoneFeed(ctx context.Context, state *config.State, feed database.Feed) error {
+	now := time.Now()
+
+	tx, err := state.Db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	queries := txQueries(state, tx)
+
+	err = queries.DisableFeed(ctx, database.DisableFeedParams{
+		ID:             feed.ID,
+		DisabledAt:     sql.NullTime{Time: now, Valid: true},
+		DisabledReason: "410 Gone",
+	})
+
+	if err != nil {
+		return err
+	}
+
+	err = queries.NotifyFeedFollowers(ctx, database.NotifyFeedFollowersParams{
+		FeedID:    feed.ID,
+		CreatedAt: now,
+		Message:   fmt.Sprintf("Feed %s (%s) is gone and is no longer fetched", feed.Name, feed.Url),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	feedLogger(feed).WarnContext(ctx, "feed is gone, disabled it")
+
+	return tx.Commit()
+}
+
+func NotificationsHandler(ctx context.Context, state *config.State, command config.Command, user database.User) error {
+	notifications, err := state.DbQueries.GetUnreadNotifications(ctx, user.ID)
+
+	if err != nil {
+		return err
+	}
+
+	if len(notifications) == 0 {
+		fmt.Println("No new notifications")
+		return nil
+	}
+
+	for _, notification := range notifications {
+		fmt.Printf("%s: %s\n", notification.CreatedAt.Format(time.RFC1123), notification.Message)
+	}
+
+	return state.DbQueries.MarkNotificationsRead(ctx, database.MarkNotificationsReadParams{
+		UserID: user.ID,
+		ReadAt: sql.NullTime{Time: time.Now(), Valid: true},
+	})
+}
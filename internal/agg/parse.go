@@ -0,0 +1,297 @@
+package agg
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// sniffLen is how much of a body is looked at to tell json from xml.
+const sniffLen = 512
+
+// parseFeed parses a feed document already in memory, see decodeFeed.
+func parseFeed(body []byte, contentType string, feedURL string) (*RSSFeed, error) {
+	feed, _, err := decodeFeed(bytes.NewReader(body), contentType, feedURL, 0)
+	return feed, err
+}
+
+// decodeFeed detects the format of a feed document from its content type or
+// root element and maps it into an RSSFeed so every format goes through the
+// same pipeline. The document is decoded as it is read, without holding the
+// whole body in memory first. Only the first maxItems items are decoded, the
+// others are skipped and counted in the returned number. Zero means no limit.
+func decodeFeed(body io.Reader, contentType string, feedURL string, maxItems int) (*RSSFeed, int, error) {
+	reader := bufio.NewReader(body)
+
+	prefix, err := reader.Peek(sniffLen)
+
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, 0, err
+	}
+
+	if isJSONFeed(contentType, prefix) {
+		jsonFeed, dropped, err := decodeJSONFeed(reader, maxItems)
+
+		if err != nil {
+			return nil, 0, err
+		}
+
+		return jsonFeedToRSS(jsonFeed, feedURL), dropped, nil
+	}
+
+	utf8Body, err := utf8Reader(reader, contentType)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	decoder := newXMLDecoder(utf8Body)
+
+	root, err := rootElement(decoder)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	switch root.Name.Local {
+	case "rss":
+		var document rssDocument
+		document.Channel.Item.max = maxItems
+
+		err = decoder.DecodeElement(&document, &root)
+
+		if err != nil {
+			return nil, 0, err
+		}
+
+		feed := RSSFeed{Channel: document.Channel.RSSChannel}
+		feed.Channel.Item = document.Channel.Item.items
+
+		return &feed, document.Channel.Item.dropped, nil
+	case "feed":
+		var document atomDocument
+		document.Entries.max = maxItems
+
+		err = decoder.DecodeElement(&document, &root)
+
+		if err != nil {
+			return nil, 0, err
+		}
+
+		atom := document.AtomFeed
+		atom.Entries = document.Entries.items
+
+		return atomToRSS(&atom, feedURL), document.Entries.dropped, nil
+	case "RDF":
+		var document rdfDocument
+		document.Item.max = maxItems
+
+		err = decoder.DecodeElement(&document, &root)
+
+		if err != nil {
+			return nil, 0, err
+		}
+
+		rdf := document.RDFFeed
+		rdf.Item = document.Item.items
+
+		return rdfToRSS(&rdf, feedURL), document.Item.dropped, nil
+	default:
+		return nil, 0, fmt.Errorf("unsupported feed format: <%s>", root.Name.Local)
+	}
+}
+
+// the documents below decode like the feed they embed, except that their
+// items go through an itemList. The shallower field wins over the embedded
+// one for the same element.
+type rssDocument struct {
+	Channel struct {
+		RSSChannel
+		Item itemList[RSSItem] `xml:"item"`
+	} `xml:"channel"`
+}
+
+type atomDocument struct {
+	AtomFeed
+	Entries itemList[AtomEntry] `xml:"entry"`
+}
+
+type rdfDocument struct {
+	RDFFeed
+	Item itemList[RDFItem] `xml:"item"`
+}
+
+// itemList decodes the first max items of a feed and skips the others, so a
+// feed with a huge number of items costs no more than max of them. Zero
+// means no limit.
+type itemList[T any] struct {
+	items   []T
+	max     int
+	dropped int
+}
+
+func (l *itemList[T]) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	if l.max > 0 && len(l.items) >= l.max {
+		l.dropped++
+		return decoder.Skip()
+	}
+
+	var item T
+
+	err := decoder.DecodeElement(&item, &start)
+
+	if err != nil {
+		return err
+	}
+
+	l.items = append(l.items, item)
+
+	return nil
+}
+
+// decodeJSONFeed walks the top level object of a json feed so its items are
+// decoded one at a time, which json.Decoder.Decode would only do after
+// buffering the whole document. Items past maxItems are skipped and counted.
+func decodeJSONFeed(body io.Reader, maxItems int) (*JSONFeed, int, error) {
+	decoder := json.NewDecoder(body)
+
+	err := expectDelim(decoder, '{')
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var items []JSONFeedItem
+	dropped := 0
+	fields := map[string]json.RawMessage{}
+
+	for decoder.More() {
+		token, err := decoder.Token()
+
+		if err != nil {
+			return nil, 0, err
+		}
+
+		key, _ := token.(string)
+
+		if key != "items" {
+			var value json.RawMessage
+
+			err = decoder.Decode(&value)
+
+			if err != nil {
+				return nil, 0, err
+			}
+
+			fields[key] = value
+			continue
+		}
+
+		token, err = decoder.Token()
+
+		if err != nil {
+			return nil, 0, err
+		}
+
+		// items may be null
+		if token == nil {
+			continue
+		}
+
+		if token != json.Delim('[') {
+			return nil, 0, errors.New("json feed items are not a list")
+		}
+
+		for decoder.More() {
+			if maxItems > 0 && len(items) >= maxItems {
+				var skipped json.RawMessage
+
+				err = decoder.Decode(&skipped)
+
+				if err != nil {
+					return nil, 0, err
+				}
+
+				dropped++
+				continue
+			}
+
+			var item JSONFeedItem
+
+			err = decoder.Decode(&item)
+
+			if err != nil {
+				return nil, 0, err
+			}
+
+			items = append(items, item)
+		}
+
+		err = expectDelim(decoder, ']')
+
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	err = expectDelim(decoder, '}')
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// the other fields are small, decode them the usual way
+	rest, err := json.Marshal(fields)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var jsonFeed JSONFeed
+
+	err = json.Unmarshal(rest, &jsonFeed)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	jsonFeed.Items = items
+
+	return &jsonFeed, dropped, nil
+}
+
+func expectDelim(decoder *json.Decoder, delim json.Delim) error {
+	token, err := decoder.Token()
+
+	if err != nil {
+		return err
+	}
+
+	if token != delim {
+		return fmt.Errorf("invalid json feed: expected %v, got %v", delim, token)
+	}
+
+	return nil
+}
+
+func rootElement(decoder *xml.Decoder) (xml.StartElement, error) {
+	for {
+		token, err := decoder.Token()
+
+		if errors.Is(err, io.EOF) {
+			return xml.StartElement{}, errors.New("empty feed document")
+		}
+
+		if err != nil {
+			return xml.StartElement{}, err
+		}
+
+		if start, ok := token.(xml.StartElement); ok {
+			return start, nil
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package agg
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/samuelea/gator/internal/config"
+	"github.com/samuelea/gator/internal/database"
+)
+
+// hostLimiter caps how many feeds from the same host are fetched at once so
+// a pool of workers does not hammer a single publisher.
+type hostLimiter struct {
+	mu    sync.Mutex
+	limit int
+	hosts map[string]chan struct{}
+}
+
+func newHostLimiter(limit int) *hostLimiter {
+	return &hostLimiter{
+		limit: limit,
+		hosts: map[string]chan struct{}{},
+	}
+}
+
+func (l *hostLimiter) acquire(feedURL string) func() {
+	host := feedURL
+
+	parsedURL, err := url.Parse(feedURL)
+	if err == nil && parsedURL.Host != "" {
+		host = parsedURL.Host
+	}
+
+	l.mu.Lock()
+	slots, ok := l.hosts[host]
+	if !ok {
+		slots = make(chan struct{}, l.limit)
+		l.hosts[host] = slots
+	}
+	l.mu.Unlock()
+
+	slots <- struct{}{}
+
+	return func() {
+		<-slots
+	}
+}
+
+type aggOptions struct {
+	// Interval and MaxInterval bound the time between two fetches of a
+	// feed, which is learnt from how often the feed publishes
+	Interval    time.Duration
+	MaxInterval time.Duration
+	Workers     int
+	Limiter     *hostLimiter
+	// WorkerID identifies this process in the leases it takes on feeds, so
+	// several agg processes can share a database without fetching the same
+	// feed twice. Leases expire after Lease in case the process dies.
+	WorkerID string
+	Lease    time.Duration
+	Stats    *aggStats
+	Limits   feedLimits
+	// FetchLogKept is how many fetch attempts are kept per feed. The body
+	// of failed fetches is only kept with FetchLogBodies.
+	FetchLogKept   int
+	FetchLogBodies bool
+}
+
+// aggStats counts what agg did since it started, for the summary logged
+// when it stops.
+type aggStats struct {
+	Fetched      atomic.Int64
+	NotModified  atomic.Int64
+	Failed       atomic.Int64
+	NewPosts     atomic.Int64
+	UpdatedPosts atomic.Int64
+}
+
+func (s *aggStats) log() {
+	slog.Info("aggregation summary",
+		"fetched", s.Fetched.Load(),
+		"not_modified", s.NotModified.Load(),
+		"failed", s.Failed.Load(),
+		"new_posts", s.NewPosts.Load(),
+		"updated_posts", s.UpdatedPosts.Load(),
+	)
+}
+
+// newWorkerID names this process after its host and pid, with a random
+// suffix in case pids are reused across containers.
+func newWorkerID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	return fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), uuid.NewString()[:8])
+}
+
+// scrapeFeeds fetches every feed whose next fetch is due. Each worker claims
+// one due feed at a time until none are left or ctx is cancelled. Only feeds
+// due when the run started are claimed, so a feed is fetched at most once
+// per run however short its interval. Feeds
+// already being fetched are finished with workCtx, which outlives ctx for a
+// grace period on shutdown.
+func scrapeFeeds(ctx context.Context, workCtx context.Context, state *config.State, opts aggOptions) error {
+	errs := make(chan error, opts.Workers)
+	startedAt := time.Now()
+
+	due, err := state.DbQueries.CountDueFeeds(ctx, sql.NullTime{Time: startedAt, Valid: true})
+
+	if err != nil {
+		return err
+	}
+
+	dueFeeds.Set(float64(due))
+	defer lastRunTimestamp.SetToCurrentTime()
+
+	var wg sync.WaitGroup
+
+	for range opts.Workers {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for ctx.Err() == nil {
+				feed, ok, err := claimFeed(ctx, state, opts, startedAt)
+
+				// claiming is interrupted by a shutdown, not failed
+				if err != nil && ctx.Err() != nil {
+					return
+				}
+
+				if err != nil {
+					errs <- err
+					return
+				}
+
+				if !ok {
+					return
+				}
+
+				release := opts.Limiter.acquire(feed.Url)
+				attempt := &fetchAttempt{FeedID: feed.ID, StartedAt: time.Now()}
+				err = scrapeFeed(workCtx, state, feed, opts, attempt)
+				release()
+
+				if err != nil {
+					opts.Stats.Failed.Add(1)
+					feedLogger(feed).ErrorContext(workCtx, "failed to scrape feed", "error", err)
+				}
+
+				observeFetch(attempt, err)
+
+				logErr := recordFetch(workCtx, state, attempt, err, opts)
+
+				if logErr != nil {
+					feedLogger(feed).ErrorContext(workCtx, "failed to record fetch", "error", logErr)
+				}
+
+				// a fetch that failed before its next fetch or retry was
+				// written keeps its lease until it expires rather than
+				// letting it be claimed again right away
+				if err != nil && !attempt.Rescheduled {
+					continue
+				}
+
+				// the feed was merged into another one and deleted
+				if attempt.FeedID != feed.ID {
+					continue
+				}
+
+				err = state.DbQueries.ReleaseFeedLease(workCtx, database.ReleaseFeedLeaseParams{
+					ID:       feed.ID,
+					LeasedBy: opts.WorkerID,
+				})
+
+				if err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	return <-errs
+}
+
+func claimFeed(ctx context.Context, state *config.State, opts aggOptions, dueAt time.Time) (database.Feed, bool, error) {
+	now := time.Now()
+
+	feeds, err := state.DbQueries.ClaimDueFeeds(ctx, database.ClaimDueFeedsParams{
+		WorkerID:       opts.WorkerID,
+		LeaseExpiresAt: sql.NullTime{Time: now.Add(opts.Lease), Valid: true},
+		Now:            sql.NullTime{Time: dueAt, Valid: true},
+		BatchSize:      1,
+	})
+
+	if err != nil || len(feeds) == 0 {
+		return database.Feed{}, false, err
+	}
+
+	return feeds[0], true, nil
+}
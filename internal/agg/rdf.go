@@ -0,0 +1,50 @@
+package agg
+
+import "strings"
+
+// RDFFeed is an RSS 1.0 document. Unlike RSS 2.0 its items are siblings of
+// the channel instead of children.
+type RDFFeed struct {
+	Channel struct {
+		Title           string `xml:"title"`
+		Link            string `xml:"link"`
+		Description     string `xml:"description"`
+		UpdatePeriod    string `xml:"http://purl.org/rss/1.0/modules/syndication/ updatePeriod"`
+		UpdateFrequency string `xml:"http://purl.org/rss/1.0/modules/syndication/ updateFrequency"`
+	} `xml:"channel"`
+	Item []RDFItem `xml:"item"`
+}
+
+type RDFItem struct {
+	About       string   `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# about,attr"`
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Description string   `xml:"description"`
+	Date        string   `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Creators    []string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Content     string   `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+}
+
+func rdfToRSS(rdf *RDFFeed, feedURL string) *RSSFeed {
+	var feed RSSFeed
+
+	feed.Channel.Title = strings.TrimSpace(rdf.Channel.Title)
+	feed.Channel.Link = resolveLink(feedURL, strings.TrimSpace(rdf.Channel.Link))
+	feed.Channel.Description = strings.TrimSpace(rdf.Channel.Description)
+	feed.Channel.UpdatePeriod = rdf.Channel.UpdatePeriod
+	feed.Channel.UpdateFrequency = rdf.Channel.UpdateFrequency
+
+	for _, item := range rdf.Item {
+		feed.Channel.Item = append(feed.Channel.Item, RSSItem{
+			Title:       strings.TrimSpace(item.Title),
+			Link:        resolveLink(feedURL, strings.TrimSpace(item.Link)),
+			Description: strings.TrimSpace(item.Description),
+			PubDate:     strings.TrimSpace(item.Date),
+			GUID:        strings.TrimSpace(item.About),
+			Content:     item.Content,
+			Creators:    item.Creators,
+		})
+	}
+
+	return &feed
+}
@@ -0,0 +1,67 @@
+package agg
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseRDFFeed(t *testing.T) {
+	body, err := os.ReadFile(filepath.Join("testdata", "news.rdf"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	feed, err := parseFeed(body, "application/rdf+xml", "https://news.example.com/index.rdf")
+
+	if err != nil {
+		t.Fatalf("parseFeed() error = %v", err)
+	}
+
+	if feed.Channel.Title != "Example News" || feed.Channel.Link != "https://news.example.com/" || feed.Channel.Description != "News for nerds" {
+		t.Errorf("channel = %q, %q, %q", feed.Channel.Title, feed.Channel.Link, feed.Channel.Description)
+	}
+
+	if feed.Channel.UpdatePeriod != "hourly" || feed.Channel.UpdateFrequency != "2" {
+		t.Errorf("update period = %q, frequency = %q", feed.Channel.UpdatePeriod, feed.Channel.UpdateFrequency)
+	}
+
+	want := []RSSItem{
+		{
+			Title:       "First story",
+			Link:        "https://news.example.com/story/1?from=rss",
+			Description: "The first story",
+			PubDate:     "2026-10-12T14:00:00+00:00",
+			GUID:        "https://news.example.com/story/1",
+			Content:     "<p>The <em>first</em> story</p>",
+			Creators:    []string{"alice", "bob"},
+		},
+		{
+			Title:       "Second story",
+			Link:        "https://news.example.com/story/2",
+			Description: "The second story",
+			PubDate:     "2026-10-12T15:30:00+00:00",
+			GUID:        "https://news.example.com/story/2",
+		},
+	}
+
+	if len(feed.Channel.Item) != len(want) {
+		t.Fatalf("got %d items, want %d", len(feed.Channel.Item), len(want))
+	}
+
+	for i, item := range feed.Channel.Item {
+		if !reflect.DeepEqual(item, want[i]) {
+			t.Errorf("item %d = %+v, want %+v", i, item, want[i])
+		}
+	}
+
+	// dc:date is what the published time of an rdf item comes from
+	published, err := parseDate(feed.Channel.Item[0].PubDate)
+
+	if err != nil || !published.Equal(time.Date(2026, 10, 12, 14, 0, 0, 0, time.UTC)) {
+		t.Errorf("parseDate() = %v, %v", published, err)
+	}
+}
@@ -0,0 +1,196 @@
+package agg
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/samuelea/gator/internal/database"
+)
+
+const (
+	// maxFetchInterval caps publisher hints so a feed claiming a ttl of a
+	// month is still looked at every week.
+	maxFetchInterval = 7 * 24 * time.Hour
+	// fetchJitter is the largest fraction of the interval added at random
+	// so feeds added together do not stay in lockstep.
+	fetchJitter = 0.1
+	// cadenceSamples is how many of the latest posts of a feed are used to
+	// learn how often it publishes
+	cadenceSamples = 20
+)
+
+// scheduleHints are what the publisher told us about how often the feed is
+// worth polling. skipHours and skipDays are bitmasks of the GMT hours and
+// days of the week (Sunday is bit 0) the feed should not be polled at.
+type scheduleHints struct {
+	TTL       time.Duration
+	SkipHours int32
+	SkipDays  int32
+}
+
+func hintsFromFeed(feed database.Feed) scheduleHints {
+	return scheduleHints{
+		TTL:       time.Duration(feed.TtlSeconds) * time.Second,
+		SkipHours: feed.SkipHours,
+		SkipDays:  feed.SkipDays,
+	}
+}
+
+func hintsFromRSS(feed *RSSFeed) scheduleHints {
+	var hints scheduleHints
+
+	ttl, err := strconv.Atoi(strings.TrimSpace(feed.Channel.TTL))
+	if err == nil && ttl > 0 {
+		hints.TTL = time.Duration(ttl) * time.Minute
+	}
+
+	hints.TTL = max(hints.TTL, syndicationInterval(feed.Channel.UpdatePeriod, feed.Channel.UpdateFrequency))
+
+	for _, hour := range feed.Channel.SkipHours {
+		hour, err := strconv.Atoi(strings.TrimSpace(hour))
+
+		// some publishers number hours 1 to 24
+		if err == nil && hour >= 0 && hour <= 24 {
+			hints.SkipHours |= 1 << (hour % 24)
+		}
+	}
+
+	for _, day := range feed.Channel.SkipDays {
+		weekday, ok := weekdays[strings.ToLower(strings.TrimSpace(day))]
+
+		if ok {
+			hints.SkipDays |= 1 << weekday
+		}
+	}
+
+	// a feed skipping every hour or day would never be fetched again
+	if hints.SkipHours == 1<<24-1 {
+		hints.SkipHours = 0
+	}
+	if hints.SkipDays == 1<<7-1 {
+		hints.SkipDays = 0
+	}
+
+	return hints
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+var updatePeriods = map[string]time.Duration{
+	"hourly":  time.Hour,
+	"daily":   24 * time.Hour,
+	"weekly":  7 * 24 * time.Hour,
+	"monthly": 30 * 24 * time.Hour,
+	"yearly":  365 * 24 * time.Hour,
+}
+
+// syndicationInterval reads the RSS syndication module, which says a feed
+// is updated updateFrequency times per updatePeriod.
+func syndicationInterval(updatePeriod string, updateFrequency string) time.Duration {
+	period, ok := updatePeriods[strings.ToLower(strings.TrimSpace(updatePeriod))]
+
+	if !ok {
+		return 0
+	}
+
+	frequency, err := strconv.Atoi(strings.TrimSpace(updateFrequency))
+	if err != nil || frequency < 1 {
+		frequency = 1
+	}
+
+	return period / time.Duration(frequency)
+}
+
+// cacheLifetime is how long the response may be cached according to its
+// Cache-Control max-age or, failing that, its Expires header. no-cache and
+// no-store win wherever they are in Cache-Control.
+func cacheLifetime(header http.Header, now time.Time) time.Duration {
+	maxAge := time.Duration(-1)
+
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+
+		if strings.EqualFold(name, "no-cache") || strings.EqualFold(name, "no-store") {
+			return 0
+		}
+
+		if strings.EqualFold(name, "max-age") && maxAge < 0 {
+			seconds, err := strconv.Atoi(strings.Trim(value, `"`))
+
+			maxAge = 0
+			if err == nil && seconds > 0 {
+				maxAge = time.Duration(seconds) * time.Second
+			}
+		}
+	}
+
+	if maxAge >= 0 {
+		return maxAge
+	}
+
+	expires, err := http.ParseTime(header.Get("Expires"))
+
+	if err != nil {
+		return 0
+	}
+
+	return max(expires.Sub(now), 0)
+}
+
+// nextFetchAt schedules the next fetch of a feed no sooner than interval,
+// the publisher ttl or the cache lifetime, and outside of its skip hours and
+// days.
+func nextFetchAt(now time.Time, interval time.Duration, hints scheduleHints, cacheFor time.Duration) time.Time {
+	wait := min(max(interval, hints.TTL, cacheFor), maxFetchInterval)
+	wait += time.Duration(rand.Float64() * fetchJitter * float64(wait))
+
+	next := now.Add(wait)
+
+	// skip hours and days are in GMT and can cover at most a week
+	for range 24 * 7 {
+		utc := next.UTC()
+
+		if hints.SkipDays&(1<<utc.Weekday()) == 0 && hints.SkipHours&(1<<utc.Hour()) == 0 {
+			break
+		}
+
+		next = utc.Truncate(time.Hour).Add(time.Hour)
+	}
+
+	return next
+}
+
+// pollInterval learns how often a feed should be polled from the publish
+// times of its latest posts, newest first. Polling at half the median gap
+// between posts catches most posts within half a gap of being published. A
+// feed that has gone quiet for longer than its usual gap slows down as well.
+// Feeds with too few posts to tell are polled as often as allowed.
+func pollInterval(publishTimes []time.Time, now time.Time, minInterval time.Duration, maxInterval time.Duration) time.Duration {
+	if len(publishTimes) < 2 {
+		return minInterval
+	}
+
+	gaps := make([]time.Duration, 0, len(publishTimes)-1)
+
+	for i := 1; i < len(publishTimes); i++ {
+		gaps = append(gaps, publishTimes[i-1].Sub(publishTimes[i]))
+	}
+
+	slices.Sort(gaps)
+
+	cadence := max(gaps[len(gaps)/2], now.Sub(publishTimes[0])/2)
+
+	return min(max(cadence/2, minInterval), maxInterval)
+}
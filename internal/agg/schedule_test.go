@@ -0,0 +1,276 @@
+package agg
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestHintsFromRSS(t *testing.T) {
+	allHours := make([]string, 24)
+	for hour := range allHours {
+		allHours[hour] = strconv.Itoa(hour)
+	}
+
+	allDays := []string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"}
+
+	tests := []struct {
+		name    string
+		channel RSSChannel
+		want    scheduleHints
+	}{
+		{name: "no hints", want: scheduleHints{}},
+		{name: "ttl in minutes", channel: RSSChannel{TTL: " 60 "}, want: scheduleHints{TTL: time.Hour}},
+		{name: "invalid ttl", channel: RSSChannel{TTL: "soon"}, want: scheduleHints{}},
+		{name: "negative ttl", channel: RSSChannel{TTL: "-5"}, want: scheduleHints{}},
+		{
+			name:    "syndication",
+			channel: RSSChannel{UpdatePeriod: "daily", UpdateFrequency: "4"},
+			want:    scheduleHints{TTL: 6 * time.Hour},
+		},
+		{
+			name:    "syndication without a frequency",
+			channel: RSSChannel{UpdatePeriod: "Hourly"},
+			want:    scheduleHints{TTL: time.Hour},
+		},
+		{
+			name:    "the longer of ttl and syndication",
+			channel: RSSChannel{TTL: "30", UpdatePeriod: "hourly"},
+			want:    scheduleHints{TTL: time.Hour},
+		},
+		{
+			name:    "skip hours",
+			channel: RSSChannel{SkipHours: []string{"0", " 3 ", "23", "25", "x"}},
+			want:    scheduleHints{SkipHours: 1<<0 | 1<<3 | 1<<23},
+		},
+		{
+			name:    "skip hours numbered 1 to 24",
+			channel: RSSChannel{SkipHours: []string{"1", "24"}},
+			want:    scheduleHints{SkipHours: 1<<1 | 1<<0},
+		},
+		{
+			name:    "skip days",
+			channel: RSSChannel{SkipDays: []string{"Saturday", " sunday ", "Someday"}},
+			want:    scheduleHints{SkipDays: 1<<time.Saturday | 1<<time.Sunday},
+		},
+		{
+			name:    "skipping every hour is ignored",
+			channel: RSSChannel{SkipHours: allHours, SkipDays: []string{"Monday"}},
+			want:    scheduleHints{SkipDays: 1 << time.Monday},
+		},
+		{
+			name:    "skipping every day is ignored",
+			channel: RSSChannel{SkipHours: []string{"2"}, SkipDays: allDays},
+			want:    scheduleHints{SkipHours: 1 << 2},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := hintsFromRSS(&RSSFeed{Channel: test.channel})
+
+			if got != test.want {
+				t.Errorf("hintsFromRSS() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCacheLifetime(t *testing.T) {
+	now := time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		header http.Header
+		want   time.Duration
+	}{
+		{name: "no headers", header: http.Header{}, want: 0},
+		{name: "max-age", header: http.Header{"Cache-Control": {"public, max-age=600"}}, want: 10 * time.Minute},
+		{name: "quoted max-age", header: http.Header{"Cache-Control": {`max-age="60"`}}, want: time.Minute},
+		{name: "invalid max-age", header: http.Header{"Cache-Control": {"max-age=soon"}}, want: 0},
+		{name: "no-cache", header: http.Header{"Cache-Control": {"no-cache, max-age=600"}}, want: 0},
+		// no-cache after max-age still means the response must not be cached
+		{name: "max-age before no-cache", header: http.Header{"Cache-Control": {"max-age=600, no-cache"}}, want: 0},
+		{name: "no-store", header: http.Header{"Cache-Control": {"No-Store"}}, want: 0},
+		{
+			name: "expires",
+			header: http.Header{
+				"Expires": {now.Add(2 * time.Hour).Format(http.TimeFormat)},
+			},
+			want: 2 * time.Hour,
+		},
+		{
+			name: "expired",
+			header: http.Header{
+				"Expires": {now.Add(-time.Hour).Format(http.TimeFormat)},
+			},
+			want: 0,
+		},
+		{
+			name: "max-age wins over expires",
+			header: http.Header{
+				"Cache-Control": {"max-age=60"},
+				"Expires":       {now.Add(2 * time.Hour).Format(http.TimeFormat)},
+			},
+			want: time.Minute,
+		},
+		{name: "invalid expires", header: http.Header{"Expires": {"0"}}, want: 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := cacheLifetime(test.header, now)
+
+			if got != test.want {
+				t.Errorf("cacheLifetime() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestNextFetchAt(t *testing.T) {
+	// a sunday
+	now := time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		// from is now unless set
+		from     time.Time
+		interval time.Duration
+		hints    scheduleHints
+		cacheFor time.Duration
+		// the next fetch is at wait plus up to fetchJitter of it, or exactly
+		// at want when skip hours or days move it
+		wait time.Duration
+		want time.Time
+	}{
+		{name: "interval", interval: 30 * time.Minute, wait: 30 * time.Minute},
+		{name: "ttl", interval: 30 * time.Minute, hints: scheduleHints{TTL: 2 * time.Hour}, wait: 2 * time.Hour},
+		{name: "cache lifetime", interval: 30 * time.Minute, cacheFor: time.Hour, wait: time.Hour},
+		{name: "capped", interval: 30 * time.Minute, hints: scheduleHints{TTL: 30 * 24 * time.Hour}, wait: maxFetchInterval},
+		{
+			name:     "skip hour",
+			interval: 30 * time.Minute,
+			hints:    scheduleHints{SkipHours: 1 << 10},
+			want:     time.Date(2026, 10, 18, 11, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "skip hours in a row",
+			interval: 30 * time.Minute,
+			hints:    scheduleHints{SkipHours: 1<<10 | 1<<11 | 1<<12},
+			want:     time.Date(2026, 10, 18, 13, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "skip day",
+			interval: 30 * time.Minute,
+			hints:    scheduleHints{SkipDays: 1 << time.Sunday},
+			want:     time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "skip days and hours",
+			interval: 30 * time.Minute,
+			hints:    scheduleHints{SkipDays: 1<<time.Sunday | 1<<time.Monday, SkipHours: 1 << 0},
+			want:     time.Date(2026, 10, 20, 1, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "skip hours are in gmt",
+			from:     now.In(time.FixedZone("CEST", 2*60*60)),
+			interval: 30 * time.Minute,
+			hints:    scheduleHints{SkipHours: 1 << 10},
+			want:     time.Date(2026, 10, 18, 11, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			from := test.from
+			if from.IsZero() {
+				from = now
+			}
+
+			got := nextFetchAt(from, test.interval, test.hints, test.cacheFor)
+
+			if !test.want.IsZero() {
+				if !got.Equal(test.want) {
+					t.Errorf("nextFetchAt() = %v, want %v", got, test.want)
+				}
+				return
+			}
+
+			earliest := now.Add(test.wait)
+			latest := earliest.Add(time.Duration(fetchJitter * float64(test.wait)))
+
+			if got.Before(earliest) || got.After(latest) {
+				t.Errorf("nextFetchAt() = %v, want between %v and %v", got, earliest, latest)
+			}
+		})
+	}
+}
+
+func TestPollInterval(t *testing.T) {
+	now := time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC)
+
+	// ago turns ages of posts, newest first, into their publish times
+	ago := func(ages ...time.Duration) []time.Time {
+		var times []time.Time
+
+		for _, age := range ages {
+			times = append(times, now.Add(-age))
+		}
+
+		return times
+	}
+
+	const minInterval = 10 * time.Minute
+	const maxInterval = 24 * time.Hour
+
+	tests := []struct {
+		name         string
+		publishTimes []time.Time
+		want         time.Duration
+	}{
+		{name: "no posts", want: minInterval},
+		{name: "one post", publishTimes: ago(time.Hour), want: minInterval},
+		{
+			name:         "hourly",
+			publishTimes: ago(0, time.Hour, 2*time.Hour, 3*time.Hour),
+			want:         30 * time.Minute,
+		},
+		{
+			name:         "faster than the minimum",
+			publishTimes: ago(0, 5*time.Minute, 10*time.Minute),
+			want:         minInterval,
+		},
+		{
+			name:         "slower than the maximum",
+			publishTimes: ago(0, 7*24*time.Hour, 14*24*time.Hour),
+			want:         maxInterval,
+		},
+		{
+			name:         "the median ignores a long gap",
+			publishTimes: ago(0, time.Hour, 2*time.Hour, 3*time.Hour, 4*time.Hour, 52*time.Hour),
+			want:         30 * time.Minute,
+		},
+		{
+			name:         "gone quiet",
+			publishTimes: ago(10*time.Hour, 11*time.Hour, 12*time.Hour, 13*time.Hour),
+			want:         150 * time.Minute,
+		},
+		{
+			name:         "quiet for less than a gap",
+			publishTimes: ago(90*time.Minute, 5*time.Hour, 9*time.Hour),
+			want:         2 * time.Hour,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := pollInterval(test.publishTimes, now, minInterval, maxInterval)
+
+			if got != test.want {
+				t.Errorf("pollInterval() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -1,8 +1,15 @@
 package config
 
 import (
+	"context"
+	"database/sql"
+	"encoding/base64"
 	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
 	"os"
 
 	"github.com/samuelea/gator/internal/database"
@@ -10,7 +17,9 @@ import (
 
 type State struct {
 	Config *Config
+	Db *sql.DB
 	DbQueries *database.Queries
+	HTTPClient *http.Client
 }
 
 type Command struct {
@@ -19,12 +28,44 @@ type Command struct {
 }
 
 type Commands struct {
-	Handlers map[string]func(*State, Command) error
+	Handlers map[string]func(context.Context, *State, Command) error
 }
 
 type Config struct {
 	DBUrl string `json:"db_url"`
 	CurrentUserName string `json:"current_user_name"`
+	DownloadDir string `json:"download_dir,omitempty"`
+	DownloadMaxBytes int64 `json:"download_max_bytes,omitempty"`
+	DownloadKeepLast int `json:"download_keep_last,omitempty"`
+	HTTP *HTTPConfig `json:"http_client,omitempty"`
+	CredentialsKey string `json:"credentials_key,omitempty"`
+	FeedMaxBytes int64 `json:"feed_max_bytes,omitempty"`
+	FeedMaxItems int `json:"feed_max_items,omitempty"`
+}
+
+// credentialsKeyEnv overrides the credentials_key field of the config file,
+// so the key can be kept out of it.
+const credentialsKeyEnv = "GATOR_CREDENTIALS_KEY"
+
+// FeedCredentialsKey returns the 32 byte key feed credentials are encrypted
+// with, given base64 encoded in GATOR_CREDENTIALS_KEY or credentials_key.
+func (c *Config) FeedCredentialsKey() ([]byte, error) {
+	encoded := os.Getenv(credentialsKeyEnv)
+	if encoded == "" {
+		encoded = c.CredentialsKey
+	}
+
+	if encoded == "" {
+		return nil, fmt.Errorf("no credentials key set. set %s or credentials_key in the config to a key made with: openssl rand -base64 32", credentialsKeyEnv)
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encoded)
+
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("the credentials key must be 32 bytes encoded in base64, eg: made with openssl rand -base64 32")
+	}
+
+	return key, nil
 }
 
 const configfileName = ".gatorconfig.json"
@@ -97,12 +138,41 @@ func write(c *Config) error {
 	return nil
 }
 
-func (c *Commands) Run(state *State, command Command) error {
+func (c *Commands) Run(ctx context.Context, state *State, command Command) error {
 	handler, ok := c.Handlers[command.Name]
 
 	if !ok {
 		return fmt.Errorf("error: command %s not found", command.Name)
 	}
 
-	return handler(state, command)
+	slog.DebugContext(ctx, "running command", "command", command.Name, "user", state.Config.CurrentUserName)
+
+	return handler(ctx, state, command)
+}
+
+// ParseFlags parses the command arguments against flags and returns the
+// positional arguments. Unlike flag.Parse, flags may come after positional
+// arguments, eg: agg 1m --workers 4
+func (c Command) ParseFlags(flags *flag.FlagSet) ([]string, error) {
+	flags.SetOutput(io.Discard)
+
+	var positional []string
+	args := c.Args
+
+	for {
+		err := flags.Parse(args)
+
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", c.Name, err)
+		}
+
+		args = flags.Args()
+
+		if len(args) == 0 {
+			return positional, nil
+		}
+
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
 }
@@ -0,0 +1,180 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Version is set at build time, eg:
+// go build -ldflags "-X github.com/samuelea/gator/internal/config.Version=v1.2.0"
+var Version = "dev"
+
+const (
+	defaultConnectTimeout = 10 * time.Second
+	defaultTimeout        = time.Minute
+	defaultContactURL     = "https://github.com/samuelea/gator"
+)
+
+// HTTPConfig is the http_client section of the config file. Timeouts are
+// durations such as "10s" or "2m".
+type HTTPConfig struct {
+	ConnectTimeout          string   `json:"connect_timeout,omitempty"`
+	Timeout                 string   `json:"timeout,omitempty"`
+	Proxy                   string   `json:"proxy,omitempty"`
+	CABundle                string   `json:"ca_bundle,omitempty"`
+	InsecureSkipVerifyHosts []string `json:"insecure_skip_verify_hosts,omitempty"`
+	UserAgent               string   `json:"user_agent,omitempty"`
+	ContactURL              string   `json:"contact_url,omitempty"`
+}
+
+// NewHTTPClient builds the client every request to publishers goes through.
+// Proxies default to the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment
+// variables when none is configured.
+func NewHTTPClient(c *HTTPConfig) (*http.Client, error) {
+	if c == nil {
+		c = &HTTPConfig{}
+	}
+
+	connectTimeout, err := parseDuration(c.ConnectTimeout, defaultConnectTimeout)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid connect_timeout: %w", err)
+	}
+
+	timeout, err := parseDuration(c.Timeout, defaultTimeout)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid timeout: %w", err)
+	}
+
+	proxy := http.ProxyFromEnvironment
+
+	if c.Proxy != "" {
+		proxyURL, err := url.Parse(c.Proxy)
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy: %w", err)
+		}
+
+		switch proxyURL.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("invalid proxy %s: scheme must be http, https, socks5 or socks5h", c.Proxy)
+		}
+
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	rootCAs, err := loadCABundle(c.CABundle)
+
+	if err != nil {
+		return nil, err
+	}
+
+	newTransport := func(insecure bool) *http.Transport {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.Proxy = proxy
+		transport.DialContext = (&net.Dialer{Timeout: connectTimeout, KeepAlive: 30 * time.Second}).DialContext
+		transport.TLSHandshakeTimeout = connectTimeout
+		// enclosure downloads lift the client timeout, but still wait no
+		// longer than it for the headers
+		transport.ResponseHeaderTimeout = timeout
+		transport.TLSClientConfig = &tls.Config{
+			RootCAs:            rootCAs,
+			InsecureSkipVerify: insecure,
+		}
+
+		return transport
+	}
+
+	insecureHosts := map[string]bool{}
+
+	for _, host := range c.InsecureSkipVerifyHosts {
+		insecureHosts[strings.ToLower(host)] = true
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &clientTransport{
+			secure:        newTransport(false),
+			insecure:      newTransport(true),
+			insecureHosts: insecureHosts,
+			userAgent:     c.userAgent(),
+		},
+	}, nil
+}
+
+func (c *HTTPConfig) userAgent() string {
+	if c.UserAgent != "" {
+		return c.UserAgent
+	}
+
+	contactURL := c.ContactURL
+	if contactURL == "" {
+		contactURL = defaultContactURL
+	}
+
+	return fmt.Sprintf("gator/%s (+%s)", Version, contactURL)
+}
+
+func parseDuration(value string, fallback time.Duration) (time.Duration, error) {
+	if value == "" {
+		return fallback, nil
+	}
+
+	return time.ParseDuration(value)
+}
+
+// loadCABundle trusts the certificates in path on top of the system ones.
+func loadCABundle(path string) (*x509.CertPool, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	pool, err := x509.SystemCertPool()
+
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+
+	bundle, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ca_bundle: %w", err)
+	}
+
+	if !pool.AppendCertsFromPEM(bundle) {
+		return nil, fmt.Errorf("no certificates found in ca_bundle %s", path)
+	}
+
+	return pool, nil
+}
+
+// clientTransport sets the User-Agent of every request and skips certificate
+// checks for the hosts configured as insecure, redirects included.
+type clientTransport struct {
+	secure        *http.Transport
+	insecure      *http.Transport
+	insecureHosts map[string]bool
+	userAgent     string
+}
+
+func (t *clientTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.Header.Get("User-Agent") == "" {
+		request = request.Clone(request.Context())
+		request.Header.Set("User-Agent", t.userAgent)
+	}
+
+	if t.insecureHosts[strings.ToLower(request.URL.Hostname())] {
+		return t.insecure.RoundTrip(request)
+	}
+
+	return t.secure.RoundTrip(request)
+}
@@ -0,0 +1,36 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+// NewLogger returns the logger gator logs to. Logs go to w, usually
+// stderr, so they stay apart from the output of commands on stdout.
+func NewLogger(w io.Writer, format string, level slog.Level) (*slog.Logger, error) {
+	options := &slog.HandlerOptions{Level: level}
+
+	switch format {
+	case "text":
+		return slog.New(slog.NewTextHandler(w, options)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, options)), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q. use text or json", format)
+	}
+}
+
+// LogLevel is the level logs are shown from, Info unless quiet or verbose.
+func LogLevel(quiet bool, verbose bool) (slog.Level, error) {
+	switch {
+	case quiet && verbose:
+		return 0, fmt.Errorf("--quiet and --verbose cannot be used together")
+	case quiet:
+		return slog.LevelWarn, nil
+	case verbose:
+		return slog.LevelDebug, nil
+	default:
+		return slog.LevelInfo, nil
+	}
+}
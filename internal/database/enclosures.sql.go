@@ -0,0 +1,213 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: enclosures.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const clearEnclosureDownload = `-- name: ClearEnclosureDownload :exec
+DELETE FROM enclosure_downloads
+WHERE user_id = $1 AND enclosure_id = $2
+`
+
+type ClearEnclosureDownloadParams struct {
+	UserID      uuid.UUID
+	EnclosureID uuid.UUID
+}
+
+func (q *Queries) ClearEnclosureDownload(ctx context.Context, arg ClearEnclosureDownloadParams) error {
+	_, err := q.db.ExecContext(ctx, clearEnclosureDownload, arg.UserID, arg.EnclosureID)
+	return err
+}
+
+const countOtherDownloadsOfFile = `-- name: CountOtherDownloadsOfFile :one
+SELECT COUNT(*) FROM enclosure_downloads
+WHERE file_path = $1 AND user_id <> $2
+`
+
+type CountOtherDownloadsOfFileParams struct {
+	FilePath string
+	UserID   uuid.UUID
+}
+
+func (q *Queries) CountOtherDownloadsOfFile(ctx context.Context, arg CountOtherDownloadsOfFileParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countOtherDownloadsOfFile, arg.FilePath, arg.UserID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const getEnclosuresForPost = `-- name: GetEnclosuresForPost :many
+SELECT id, created_at, updated_at, post_id, url, length, mime_type FROM enclosures
+WHERE post_id = $1
+ORDER BY created_at ASC
+`
+
+func (q *Queries) GetEnclosuresForPost(ctx context.Context, postID uuid.UUID) ([]Enclosure, error) {
+	rows, err := q.db.QueryContext(ctx, getEnclosuresForPost, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Enclosure
+	for rows.Next() {
+		var i Enclosure
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.PostID,
+			&i.Url,
+			&i.Length,
+			&i.MimeType,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getEnclosuresForUser = `-- name: GetEnclosuresForUser :many
+SELECT enclosures.id, enclosures.created_at, enclosures.updated_at, enclosures.post_id, enclosures.url, enclosures.length, enclosures.mime_type,
+       posts.feed_id,
+       posts.published_at,
+       feeds.name AS feed_name,
+       feed_follows.download_keep_last,
+       enclosure_downloads.downloaded_at,
+       COALESCE(enclosure_downloads.file_path, '')::TEXT AS file_path
+FROM enclosures
+INNER JOIN posts ON posts.id = enclosures.post_id
+INNER JOIN feeds ON feeds.id = posts.feed_id
+INNER JOIN feed_follows ON feed_follows.feed_id = feeds.id
+LEFT JOIN enclosure_downloads ON enclosure_downloads.enclosure_id = enclosures.id
+  AND enclosure_downloads.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+ORDER BY posts.feed_id, posts.published_at DESC
+`
+
+type GetEnclosuresForUserRow struct {
+	ID               uuid.UUID
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	PostID           uuid.UUID
+	Url              string
+	Length           int64
+	MimeType         string
+	FeedID           uuid.UUID
+	PublishedAt      time.Time
+	FeedName         string
+	DownloadKeepLast sql.NullInt32
+	DownloadedAt     sql.NullTime
+	FilePath         string
+}
+
+func (q *Queries) GetEnclosuresForUser(ctx context.Context, userID uuid.UUID) ([]GetEnclosuresForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getEnclosuresForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetEnclosuresForUserRow
+	for rows.Next() {
+		var i GetEnclosuresForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.PostID,
+			&i.Url,
+			&i.Length,
+			&i.MimeType,
+			&i.FeedID,
+			&i.PublishedAt,
+			&i.FeedName,
+			&i.DownloadKeepLast,
+			&i.DownloadedAt,
+			&i.FilePath,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markEnclosureDownloaded = `-- name: MarkEnclosureDownloaded :exec
+INSERT INTO enclosure_downloads (user_id, enclosure_id, file_path, downloaded_at)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (user_id, enclosure_id) DO UPDATE
+SET file_path = EXCLUDED.file_path,
+    downloaded_at = EXCLUDED.downloaded_at
+`
+
+type MarkEnclosureDownloadedParams struct {
+	UserID       uuid.UUID
+	EnclosureID  uuid.UUID
+	FilePath     string
+	DownloadedAt time.Time
+}
+
+func (q *Queries) MarkEnclosureDownloaded(ctx context.Context, arg MarkEnclosureDownloadedParams) error {
+	_, err := q.db.ExecContext(ctx, markEnclosureDownloaded,
+		arg.UserID,
+		arg.EnclosureID,
+		arg.FilePath,
+		arg.DownloadedAt,
+	)
+	return err
+}
+
+const upsertEnclosure = `-- name: UpsertEnclosure :exec
+INSERT INTO enclosures (id, created_at, updated_at, post_id, url, length, mime_type)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (post_id, url) DO UPDATE
+SET length = EXCLUDED.length,
+    mime_type = EXCLUDED.mime_type,
+    updated_at = EXCLUDED.updated_at
+WHERE enclosures.length <> EXCLUDED.length
+   OR enclosures.mime_type <> EXCLUDED.mime_type
+`
+
+type UpsertEnclosureParams struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	PostID    uuid.UUID
+	Url       string
+	Length    int64
+	MimeType  string
+}
+
+func (q *Queries) UpsertEnclosure(ctx context.Context, arg UpsertEnclosureParams) error {
+	_, err := q.db.ExecContext(ctx, upsertEnclosure,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.PostID,
+		arg.Url,
+		arg.Length,
+		arg.MimeType,
+	)
+	return err
+}
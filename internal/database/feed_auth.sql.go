@@ -0,0 +1,65 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: feed_auth.sql
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const deleteFeedAuth = `-- name: DeleteFeedAuth :exec
+DELETE FROM feed_auth
+WHERE feed_id = $1
+`
+
+func (q *Queries) DeleteFeedAuth(ctx context.Context, feedID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteFeedAuth, feedID)
+	return err
+}
+
+const getFeedAuth = `-- name: GetFeedAuth :one
+SELECT feed_id, created_at, updated_at, credentials FROM feed_auth
+WHERE feed_id = $1
+`
+
+func (q *Queries) GetFeedAuth(ctx context.Context, feedID uuid.UUID) (FeedAuth, error) {
+	row := q.db.QueryRowContext(ctx, getFeedAuth, feedID)
+	var i FeedAuth
+	err := row.Scan(
+		&i.FeedID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Credentials,
+	)
+	return i, err
+}
+
+const upsertFeedAuth = `-- name: UpsertFeedAuth :exec
+INSERT INTO feed_auth (feed_id, created_at, updated_at, credentials)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (feed_id) DO UPDATE
+SET credentials = EXCLUDED.credentials,
+    updated_at = EXCLUDED.updated_at
+`
+
+type UpsertFeedAuthParams struct {
+	FeedID      uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Credentials []byte
+}
+
+func (q *Queries) UpsertFeedAuth(ctx context.Context, arg UpsertFeedAuthParams) error {
+	_, err := q.db.ExecContext(ctx, upsertFeedAuth,
+		arg.FeedID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Credentials,
+	)
+	return err
+}
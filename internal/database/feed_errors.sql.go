@@ -0,0 +1,96 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: feed_errors.sql
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const createFeedError = `-- name: CreateFeedError :exec
+INSERT INTO feed_errors (id, created_at, feed_id, error)
+VALUES ($1, $2, $3, $4)
+`
+
+type CreateFeedErrorParams struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	FeedID    uuid.UUID
+	Error     string
+}
+
+func (q *Queries) CreateFeedError(ctx context.Context, arg CreateFeedErrorParams) error {
+	_, err := q.db.ExecContext(ctx, createFeedError,
+		arg.ID,
+		arg.CreatedAt,
+		arg.FeedID,
+		arg.Error,
+	)
+	return err
+}
+
+const getFeedErrors = `-- name: GetFeedErrors :many
+SELECT id, created_at, feed_id, error FROM feed_errors
+WHERE feed_id = $1
+ORDER BY created_at DESC
+LIMIT $2
+`
+
+type GetFeedErrorsParams struct {
+	FeedID uuid.UUID
+	Limit  int32
+}
+
+func (q *Queries) GetFeedErrors(ctx context.Context, arg GetFeedErrorsParams) ([]FeedError, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedErrors, arg.FeedID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FeedError
+	for rows.Next() {
+		var i FeedError
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.FeedID,
+			&i.Error,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const trimFeedErrors = `-- name: TrimFeedErrors :exec
+DELETE FROM feed_errors
+WHERE feed_errors.feed_id = $1
+  AND feed_errors.id NOT IN (
+    SELECT recent.id FROM feed_errors AS recent
+    WHERE recent.feed_id = $1
+    ORDER BY recent.created_at DESC
+    LIMIT $2
+  )
+`
+
+type TrimFeedErrorsParams struct {
+	FeedID uuid.UUID
+	Limit  int32
+}
+
+func (q *Queries) TrimFeedErrors(ctx context.Context, arg TrimFeedErrorsParams) error {
+	_, err := q.db.ExecContext(ctx, trimFeedErrors, arg.FeedID, arg.Limit)
+	return err
+}
@@ -7,6 +7,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -17,10 +18,10 @@ const createFeedFollow = `-- name: CreateFeedFollow :one
 WITH new_feed_follow AS (
   INSERT INTO feed_follows (id, created_at, updated_at, feed_id, user_id)
   VALUES ($1, $2, $3, $4, $5)
-  RETURNING id, created_at, updated_at, feed_id, user_id
+  RETURNING id, created_at, updated_at, feed_id, user_id, download_keep_last
 )
 
-SELECT new_feed_follow.id, new_feed_follow.created_at, new_feed_follow.updated_at, new_feed_follow.feed_id, new_feed_follow.user_id, 
+SELECT new_feed_follow.id, new_feed_follow.created_at, new_feed_follow.updated_at, new_feed_follow.feed_id, new_feed_follow.user_id, new_feed_follow.download_keep_last, 
 feeds.name AS feed_name,
 users.name AS user_name
 FROM new_feed_follow
@@ -37,13 +38,14 @@ type CreateFeedFollowParams struct {
 }
 
 type CreateFeedFollowRow struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	FeedID    uuid.UUID
-	UserID    uuid.UUID
-	FeedName  string
-	UserName  string
+	ID               uuid.UUID
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	FeedID           uuid.UUID
+	UserID           uuid.UUID
+	DownloadKeepLast sql.NullInt32
+	FeedName         string
+	UserName         string
 }
 
 func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowParams) (CreateFeedFollowRow, error) {
@@ -61,6 +63,7 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 		&i.UpdatedAt,
 		&i.FeedID,
 		&i.UserID,
+		&i.DownloadKeepLast,
 		&i.FeedName,
 		&i.UserName,
 	)
@@ -82,7 +85,7 @@ func (q *Queries) DeleteFeedFollow(ctx context.Context, arg DeleteFeedFollowPara
 }
 
 const getFeedFollows = `-- name: GetFeedFollows :many
-SELECT id, created_at, updated_at, feed_id, user_id FROM feed_follows
+SELECT id, created_at, updated_at, feed_id, user_id, download_keep_last FROM feed_follows
 `
 
 func (q *Queries) GetFeedFollows(ctx context.Context) ([]FeedFollow, error) {
@@ -100,6 +103,7 @@ func (q *Queries) GetFeedFollows(ctx context.Context) ([]FeedFollow, error) {
 			&i.UpdatedAt,
 			&i.FeedID,
 			&i.UserID,
+			&i.DownloadKeepLast,
 		); err != nil {
 			return nil, err
 		}
@@ -115,7 +119,7 @@ func (q *Queries) GetFeedFollows(ctx context.Context) ([]FeedFollow, error) {
 }
 
 const getFeedFollowsForUser = `-- name: GetFeedFollowsForUser :many
-SELECT feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.feed_id, feed_follows.user_id, 
+SELECT feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.feed_id, feed_follows.user_id, feed_follows.download_keep_last, 
        feeds.name as feed_name,
        feeds.url as feed_url
 FROM feed_follows
@@ -124,13 +128,14 @@ WHERE feed_follows.user_id = $1
 `
 
 type GetFeedFollowsForUserRow struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	FeedID    uuid.UUID
-	UserID    uuid.UUID
-	FeedName  string
-	FeedUrl   string
+	ID               uuid.UUID
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	FeedID           uuid.UUID
+	UserID           uuid.UUID
+	DownloadKeepLast sql.NullInt32
+	FeedName         string
+	FeedUrl          string
 }
 
 func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]GetFeedFollowsForUserRow, error) {
@@ -148,6 +153,7 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 			&i.UpdatedAt,
 			&i.FeedID,
 			&i.UserID,
+			&i.DownloadKeepLast,
 			&i.FeedName,
 			&i.FeedUrl,
 		); err != nil {
@@ -163,3 +169,48 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 	}
 	return items, nil
 }
+
+const moveFeedFollows = `-- name: MoveFeedFollows :exec
+INSERT INTO feed_follows (id, created_at, updated_at, feed_id, user_id, download_keep_last)
+SELECT uuid_generate_v4(), $1, $1, $2, feed_follows.user_id, feed_follows.download_keep_last
+FROM feed_follows
+WHERE feed_follows.feed_id = $3
+ON CONFLICT (feed_id, user_id) DO NOTHING
+`
+
+type MoveFeedFollowsParams struct {
+	CreatedAt  time.Time
+	ToFeedID   uuid.UUID
+	FromFeedID uuid.UUID
+}
+
+func (q *Queries) MoveFeedFollows(ctx context.Context, arg MoveFeedFollowsParams) error {
+	_, err := q.db.ExecContext(ctx, moveFeedFollows, arg.CreatedAt, arg.ToFeedID, arg.FromFeedID)
+	return err
+}
+
+const setFeedFollowKeepLast = `-- name: SetFeedFollowKeepLast :execrows
+UPDATE feed_follows
+SET download_keep_last = $3, updated_at = $4
+WHERE feed_id = $1 AND user_id = $2
+`
+
+type SetFeedFollowKeepLastParams struct {
+	FeedID           uuid.UUID
+	UserID           uuid.UUID
+	DownloadKeepLast sql.NullInt32
+	UpdatedAt        time.Time
+}
+
+func (q *Queries) SetFeedFollowKeepLast(ctx context.Context, arg SetFeedFollowKeepLastParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setFeedFollowKeepLast,
+		arg.FeedID,
+		arg.UserID,
+		arg.DownloadKeepLast,
+		arg.UpdatedAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
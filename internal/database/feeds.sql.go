@@ -13,10 +13,101 @@ import (
 	"github.com/google/uuid"
 )
 
+const claimDueFeeds = `-- name: ClaimDueFeeds :many
+UPDATE feeds
+SET leased_by = $1, lease_expires_at = $2
+WHERE feeds.id IN (
+  SELECT due.id FROM feeds AS due
+  WHERE (due.next_fetch_at IS NULL OR due.next_fetch_at <= $3)
+    AND (due.next_retry_at IS NULL OR due.next_retry_at <= $3)
+    AND (due.lease_expires_at IS NULL OR due.lease_expires_at <= $3)
+    AND due.disabled_at IS NULL
+  ORDER BY due.last_fetched_at ASC NULLS FIRST
+  LIMIT $4
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, content_hash, consecutive_failures, last_error, last_error_at, last_success_at, next_retry_at, disabled_at, disabled_reason, next_fetch_at, ttl_seconds, skip_hours, skip_days, poll_interval_seconds, leased_by, lease_expires_at
+`
+
+type ClaimDueFeedsParams struct {
+	WorkerID       string
+	LeaseExpiresAt sql.NullTime
+	Now            sql.NullTime
+	BatchSize      int32
+}
+
+func (q *Queries) ClaimDueFeeds(ctx context.Context, arg ClaimDueFeedsParams) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, claimDueFeeds,
+		arg.WorkerID,
+		arg.LeaseExpiresAt,
+		arg.Now,
+		arg.BatchSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.ContentHash,
+			&i.ConsecutiveFailures,
+			&i.LastError,
+			&i.LastErrorAt,
+			&i.LastSuccessAt,
+			&i.NextRetryAt,
+			&i.DisabledAt,
+			&i.DisabledReason,
+			&i.NextFetchAt,
+			&i.TtlSeconds,
+			&i.SkipHours,
+			&i.SkipDays,
+			&i.PollIntervalSeconds,
+			&i.LeasedBy,
+			&i.LeaseExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const countDueFeeds = `-- name: CountDueFeeds :one
+SELECT COUNT(*) FROM feeds
+WHERE (next_fetch_at IS NULL OR next_fetch_at <= $1)
+  AND (next_retry_at IS NULL OR next_retry_at <= $1)
+  AND (lease_expires_at IS NULL OR lease_expires_at <= $1)
+  AND disabled_at IS NULL
+`
+
+func (q *Queries) CountDueFeeds(ctx context.Context, now sql.NullTime) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countDueFeeds, now)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createFeed = `-- name: CreateFeed :one
 INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
 VALUES ($1, $2, $3, $4, $5, $6)
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, content_hash, consecutive_failures, last_error, last_error_at, last_success_at, next_retry_at, disabled_at, disabled_reason, next_fetch_at, ttl_seconds, skip_hours, skip_days, poll_interval_seconds, leased_by, lease_expires_at
 `
 
 type CreateFeedParams struct {
@@ -46,12 +137,55 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.ContentHash,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.LastErrorAt,
+		&i.LastSuccessAt,
+		&i.NextRetryAt,
+		&i.DisabledAt,
+		&i.DisabledReason,
+		&i.NextFetchAt,
+		&i.TtlSeconds,
+		&i.SkipHours,
+		&i.SkipDays,
+		&i.PollIntervalSeconds,
+		&i.LeasedBy,
+		&i.LeaseExpiresAt,
 	)
 	return i, err
 }
 
+const deleteFeed = `-- name: DeleteFeed :exec
+DELETE FROM feeds WHERE id = $1
+`
+
+func (q *Queries) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteFeed, id)
+	return err
+}
+
+const disableFeed = `-- name: DisableFeed :exec
+UPDATE feeds
+SET disabled_at = $2, updated_at = $2, disabled_reason = $3
+WHERE id = $1
+`
+
+type DisableFeedParams struct {
+	ID             uuid.UUID
+	DisabledAt     sql.NullTime
+	DisabledReason string
+}
+
+func (q *Queries) DisableFeed(ctx context.Context, arg DisableFeedParams) error {
+	_, err := q.db.ExecContext(ctx, disableFeed, arg.ID, arg.DisabledAt, arg.DisabledReason)
+	return err
+}
+
 const feedFromUrl = `-- name: FeedFromUrl :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds WHERE url = $1
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, content_hash, consecutive_failures, last_error, last_error_at, last_success_at, next_retry_at, disabled_at, disabled_reason, next_fetch_at, ttl_seconds, skip_hours, skip_days, poll_interval_seconds, leased_by, lease_expires_at FROM feeds WHERE url = $1
 `
 
 func (q *Queries) FeedFromUrl(ctx context.Context, url string) (Feed, error) {
@@ -65,16 +199,35 @@ func (q *Queries) FeedFromUrl(ctx context.Context, url string) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.ContentHash,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.LastErrorAt,
+		&i.LastSuccessAt,
+		&i.NextRetryAt,
+		&i.DisabledAt,
+		&i.DisabledReason,
+		&i.NextFetchAt,
+		&i.TtlSeconds,
+		&i.SkipHours,
+		&i.SkipDays,
+		&i.PollIntervalSeconds,
+		&i.LeasedBy,
+		&i.LeaseExpiresAt,
 	)
 	return i, err
 }
 
-const getFeeds = `-- name: GetFeeds :many
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
+const getFailingFeeds = `-- name: GetFailingFeeds :many
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, content_hash, consecutive_failures, last_error, last_error_at, last_success_at, next_retry_at, disabled_at, disabled_reason, next_fetch_at, ttl_seconds, skip_hours, skip_days, poll_interval_seconds, leased_by, lease_expires_at FROM feeds
+WHERE consecutive_failures > 0
+ORDER BY consecutive_failures DESC, name
 `
 
-func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
-	rows, err := q.db.QueryContext(ctx, getFeeds)
+func (q *Queries) GetFailingFeeds(ctx context.Context) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getFailingFeeds)
 	if err != nil {
 		return nil, err
 	}
@@ -90,6 +243,23 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
 			&i.Url,
 			&i.UserID,
 			&i.LastFetchedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.ContentHash,
+			&i.ConsecutiveFailures,
+			&i.LastError,
+			&i.LastErrorAt,
+			&i.LastSuccessAt,
+			&i.NextRetryAt,
+			&i.DisabledAt,
+			&i.DisabledReason,
+			&i.NextFetchAt,
+			&i.TtlSeconds,
+			&i.SkipHours,
+			&i.SkipDays,
+			&i.PollIntervalSeconds,
+			&i.LeasedBy,
+			&i.LeaseExpiresAt,
 		); err != nil {
 			return nil, err
 		}
@@ -104,29 +274,65 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
 	return items, nil
 }
 
-const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds 
-ORDER BY last_fetched_at DESC NULLS FIRST
+const getFeeds = `-- name: GetFeeds :many
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, content_hash, consecutive_failures, last_error, last_error_at, last_success_at, next_retry_at, disabled_at, disabled_reason, next_fetch_at, ttl_seconds, skip_hours, skip_days, poll_interval_seconds, leased_by, lease_expires_at FROM feeds
 `
 
-func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
-	row := q.db.QueryRowContext(ctx, getNextFeedToFetch)
-	var i Feed
-	err := row.Scan(
-		&i.ID,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-		&i.Name,
-		&i.Url,
-		&i.UserID,
-		&i.LastFetchedAt,
-	)
-	return i, err
+func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getFeeds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.ContentHash,
+			&i.ConsecutiveFailures,
+			&i.LastError,
+			&i.LastErrorAt,
+			&i.LastSuccessAt,
+			&i.NextRetryAt,
+			&i.DisabledAt,
+			&i.DisabledReason,
+			&i.NextFetchAt,
+			&i.TtlSeconds,
+			&i.SkipHours,
+			&i.SkipDays,
+			&i.PollIntervalSeconds,
+			&i.LeasedBy,
+			&i.LeaseExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
 const markFeedFetched = `-- name: MarkFeedFetched :exec
 UPDATE feeds
-SET last_fetched_at = $2, updated_at = $2
+SET last_fetched_at = $2,
+    updated_at = $2,
+    last_success_at = $2,
+    consecutive_failures = 0,
+    next_retry_at = NULL
 WHERE id = $1
 `
 
@@ -139,3 +345,114 @@ func (q *Queries) MarkFeedFetched(ctx context.Context, arg MarkFeedFetchedParams
 	_, err := q.db.ExecContext(ctx, markFeedFetched, arg.ID, arg.LastFetchedAt)
 	return err
 }
+
+const recordFeedFailure = `-- name: RecordFeedFailure :exec
+UPDATE feeds
+SET last_fetched_at = $2,
+    updated_at = $2,
+    last_error_at = $2,
+    consecutive_failures = consecutive_failures + 1,
+    last_error = $3,
+    next_retry_at = $4
+WHERE id = $1
+`
+
+type RecordFeedFailureParams struct {
+	ID            uuid.UUID
+	LastFetchedAt sql.NullTime
+	LastError     string
+	NextRetryAt   sql.NullTime
+}
+
+func (q *Queries) RecordFeedFailure(ctx context.Context, arg RecordFeedFailureParams) error {
+	_, err := q.db.ExecContext(ctx, recordFeedFailure,
+		arg.ID,
+		arg.LastFetchedAt,
+		arg.LastError,
+		arg.NextRetryAt,
+	)
+	return err
+}
+
+const releaseFeedLease = `-- name: ReleaseFeedLease :exec
+UPDATE feeds
+SET leased_by = '', lease_expires_at = NULL
+WHERE id = $1 AND leased_by = $2
+`
+
+type ReleaseFeedLeaseParams struct {
+	ID       uuid.UUID
+	LeasedBy string
+}
+
+func (q *Queries) ReleaseFeedLease(ctx context.Context, arg ReleaseFeedLeaseParams) error {
+	_, err := q.db.ExecContext(ctx, releaseFeedLease, arg.ID, arg.LeasedBy)
+	return err
+}
+
+const updateFeedCacheValidators = `-- name: UpdateFeedCacheValidators :exec
+UPDATE feeds
+SET etag = $2, last_modified = $3, content_hash = $4
+WHERE id = $1
+`
+
+type UpdateFeedCacheValidatorsParams struct {
+	ID           uuid.UUID
+	Etag         string
+	LastModified string
+	ContentHash  string
+}
+
+func (q *Queries) UpdateFeedCacheValidators(ctx context.Context, arg UpdateFeedCacheValidatorsParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedCacheValidators,
+		arg.ID,
+		arg.Etag,
+		arg.LastModified,
+		arg.ContentHash,
+	)
+	return err
+}
+
+const updateFeedSchedule = `-- name: UpdateFeedSchedule :exec
+UPDATE feeds
+SET next_fetch_at = $2, ttl_seconds = $3, skip_hours = $4, skip_days = $5, poll_interval_seconds = $6
+WHERE id = $1
+`
+
+type UpdateFeedScheduleParams struct {
+	ID                  uuid.UUID
+	NextFetchAt         sql.NullTime
+	TtlSeconds          int32
+	SkipHours           int32
+	SkipDays            int32
+	PollIntervalSeconds int32
+}
+
+func (q *Queries) UpdateFeedSchedule(ctx context.Context, arg UpdateFeedScheduleParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedSchedule,
+		arg.ID,
+		arg.NextFetchAt,
+		arg.TtlSeconds,
+		arg.SkipHours,
+		arg.SkipDays,
+		arg.PollIntervalSeconds,
+	)
+	return err
+}
+
+const updateFeedUrl = `-- name: UpdateFeedUrl :exec
+UPDATE feeds
+SET url = $2, updated_at = $3
+WHERE id = $1
+`
+
+type UpdateFeedUrlParams struct {
+	ID        uuid.UUID
+	Url       string
+	UpdatedAt time.Time
+}
+
+func (q *Queries) UpdateFeedUrl(ctx context.Context, arg UpdateFeedUrlParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedUrl, arg.ID, arg.Url, arg.UpdatedAt)
+	return err
+}
@@ -0,0 +1,139 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: fetch_log.sql
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const createFetchLog = `-- name: CreateFetchLog :exec
+INSERT INTO fetch_log (id, feed_id, started_at, duration_ms, http_status, bytes, items_seen, items_new, items_dropped, error, body)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+`
+
+type CreateFetchLogParams struct {
+	ID           uuid.UUID
+	FeedID       uuid.UUID
+	StartedAt    time.Time
+	DurationMs   int32
+	HttpStatus   int32
+	Bytes        int64
+	ItemsSeen    int32
+	ItemsNew     int32
+	ItemsDropped int32
+	Error        string
+	Body         []byte
+}
+
+func (q *Queries) CreateFetchLog(ctx context.Context, arg CreateFetchLogParams) error {
+	_, err := q.db.ExecContext(ctx, createFetchLog,
+		arg.ID,
+		arg.FeedID,
+		arg.StartedAt,
+		arg.DurationMs,
+		arg.HttpStatus,
+		arg.Bytes,
+		arg.ItemsSeen,
+		arg.ItemsNew,
+		arg.ItemsDropped,
+		arg.Error,
+		arg.Body,
+	)
+	return err
+}
+
+const getFetchLog = `-- name: GetFetchLog :many
+SELECT fetch_log.id, fetch_log.feed_id, fetch_log.started_at, fetch_log.duration_ms, fetch_log.http_status, fetch_log.bytes, fetch_log.items_seen, fetch_log.items_new, fetch_log.error, fetch_log.body, fetch_log.items_dropped, feeds.name AS feed_name, feeds.url AS feed_url
+FROM fetch_log
+INNER JOIN feeds ON fetch_log.feed_id = feeds.id
+WHERE ($1::uuid IS NULL OR fetch_log.feed_id = $1)
+  AND (NOT $2::boolean OR fetch_log.error <> '')
+ORDER BY fetch_log.started_at DESC
+LIMIT $3
+`
+
+type GetFetchLogParams struct {
+	FeedID     uuid.NullUUID
+	FailedOnly bool
+	RowLimit   int32
+}
+
+type GetFetchLogRow struct {
+	ID           uuid.UUID
+	FeedID       uuid.UUID
+	StartedAt    time.Time
+	DurationMs   int32
+	HttpStatus   int32
+	Bytes        int64
+	ItemsSeen    int32
+	ItemsNew     int32
+	Error        string
+	Body         []byte
+	ItemsDropped int32
+	FeedName     string
+	FeedUrl      string
+}
+
+func (q *Queries) GetFetchLog(ctx context.Context, arg GetFetchLogParams) ([]GetFetchLogRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFetchLog, arg.FeedID, arg.FailedOnly, arg.RowLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFetchLogRow
+	for rows.Next() {
+		var i GetFetchLogRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.FeedID,
+			&i.StartedAt,
+			&i.DurationMs,
+			&i.HttpStatus,
+			&i.Bytes,
+			&i.ItemsSeen,
+			&i.ItemsNew,
+			&i.Error,
+			&i.Body,
+			&i.ItemsDropped,
+			&i.FeedName,
+			&i.FeedUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const trimFetchLog = `-- name: TrimFetchLog :exec
+DELETE FROM fetch_log
+WHERE fetch_log.feed_id = $1
+  AND fetch_log.id NOT IN (
+    SELECT recent.id FROM fetch_log AS recent
+    WHERE recent.feed_id = $1
+    ORDER BY recent.started_at DESC
+    LIMIT $2
+  )
+`
+
+type TrimFetchLogParams struct {
+	FeedID uuid.UUID
+	Limit  int32
+}
+
+func (q *Queries) TrimFetchLog(ctx context.Context, arg TrimFetchLogParams) error {
+	_, err := q.db.ExecContext(ctx, trimFetchLog, arg.FeedID, arg.Limit)
+	return err
+}
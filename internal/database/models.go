@@ -11,22 +11,93 @@ import (
 	"github.com/google/uuid"
 )
 
+type Enclosure struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	PostID    uuid.UUID
+	Url       string
+	Length    int64
+	MimeType  string
+}
+
+type EnclosureDownload struct {
+	UserID       uuid.UUID
+	EnclosureID  uuid.UUID
+	FilePath     string
+	DownloadedAt time.Time
+}
+
 type Feed struct {
-	ID            uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	Name          string
-	Url           string
-	UserID        uuid.UUID
-	LastFetchedAt sql.NullTime
+	ID                  uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Name                string
+	Url                 string
+	UserID              uuid.UUID
+	LastFetchedAt       sql.NullTime
+	Etag                string
+	LastModified        string
+	ContentHash         string
+	ConsecutiveFailures int32
+	LastError           string
+	LastErrorAt         sql.NullTime
+	LastSuccessAt       sql.NullTime
+	NextRetryAt         sql.NullTime
+	DisabledAt          sql.NullTime
+	DisabledReason      string
+	NextFetchAt         sql.NullTime
+	TtlSeconds          int32
+	SkipHours           int32
+	SkipDays            int32
+	PollIntervalSeconds int32
+	LeasedBy            string
+	LeaseExpiresAt      sql.NullTime
 }
 
-type FeedFollow struct {
+type FeedAuth struct {
+	FeedID      uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Credentials []byte
+}
+
+type FeedError struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
-	UpdatedAt time.Time
 	FeedID    uuid.UUID
+	Error     string
+}
+
+type FeedFollow struct {
+	ID               uuid.UUID
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	FeedID           uuid.UUID
+	UserID           uuid.UUID
+	DownloadKeepLast sql.NullInt32
+}
+
+type FetchLog struct {
+	ID           uuid.UUID
+	FeedID       uuid.UUID
+	StartedAt    time.Time
+	DurationMs   int32
+	HttpStatus   int32
+	Bytes        int64
+	ItemsSeen    int32
+	ItemsNew     int32
+	Error        string
+	Body         []byte
+	ItemsDropped int32
+}
+
+type Notification struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
 	UserID    uuid.UUID
+	Message   string
+	ReadAt    sql.NullTime
 }
 
 type Post struct {
@@ -38,6 +109,19 @@ type Post struct {
 	Description string
 	PublishedAt time.Time
 	FeedID      uuid.UUID
+	Guid        string
+	RevisedAt   sql.NullTime
+	Content     string
+	Author      string
+}
+
+type PostRevision struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	PostID      uuid.UUID
+	Title       string
+	Description string
+	Content     string
 }
 
 type User struct {
@@ -0,0 +1,83 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: notifications.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const getUnreadNotifications = `-- name: GetUnreadNotifications :many
+SELECT id, created_at, user_id, message, read_at FROM notifications
+WHERE user_id = $1 AND read_at IS NULL
+ORDER BY created_at ASC
+`
+
+func (q *Queries) GetUnreadNotifications(ctx context.Context, userID uuid.UUID) ([]Notification, error) {
+	rows, err := q.db.QueryContext(ctx, getUnreadNotifications, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Notification
+	for rows.Next() {
+		var i Notification
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UserID,
+			&i.Message,
+			&i.ReadAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markNotificationsRead = `-- name: MarkNotificationsRead :exec
+UPDATE notifications
+SET read_at = $2
+WHERE user_id = $1 AND read_at IS NULL
+`
+
+type MarkNotificationsReadParams struct {
+	UserID uuid.UUID
+	ReadAt sql.NullTime
+}
+
+func (q *Queries) MarkNotificationsRead(ctx context.Context, arg MarkNotificationsReadParams) error {
+	_, err := q.db.ExecContext(ctx, markNotificationsRead, arg.UserID, arg.ReadAt)
+	return err
+}
+
+const notifyFeedFollowers = `-- name: NotifyFeedFollowers :exec
+INSERT INTO notifications (created_at, user_id, message)
+SELECT $2, feed_follows.user_id, $3
+FROM feed_follows
+WHERE feed_follows.feed_id = $1
+`
+
+type NotifyFeedFollowersParams struct {
+	FeedID    uuid.UUID
+	CreatedAt time.Time
+	Message   string
+}
+
+func (q *Queries) NotifyFeedFollowers(ctx context.Context, arg NotifyFeedFollowersParams) error {
+	_, err := q.db.ExecContext(ctx, notifyFeedFollowers, arg.FeedID, arg.CreatedAt, arg.Message)
+	return err
+}
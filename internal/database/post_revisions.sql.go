@@ -0,0 +1,84 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: post_revisions.sql
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const createPostRevision = `-- name: CreatePostRevision :execrows
+INSERT INTO post_revisions (id, created_at, post_id, title, description, content)
+SELECT $1, $2, posts.id, posts.title, posts.description, posts.content
+FROM posts
+WHERE posts.feed_id = $3
+  AND posts.guid = $4
+  AND (posts.title <> $5 OR posts.description <> $6 OR (posts.content <> $7 AND posts.content <> ''))
+`
+
+type CreatePostRevisionParams struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	FeedID      uuid.UUID
+	Guid        string
+	Title       string
+	Description string
+	Content     string
+}
+
+func (q *Queries) CreatePostRevision(ctx context.Context, arg CreatePostRevisionParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, createPostRevision,
+		arg.ID,
+		arg.CreatedAt,
+		arg.FeedID,
+		arg.Guid,
+		arg.Title,
+		arg.Description,
+		arg.Content,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getPostRevisions = `-- name: GetPostRevisions :many
+SELECT id, created_at, post_id, title, description, content FROM post_revisions
+WHERE post_id = $1
+ORDER BY created_at ASC
+`
+
+func (q *Queries) GetPostRevisions(ctx context.Context, postID uuid.UUID) ([]PostRevision, error) {
+	rows, err := q.db.QueryContext(ctx, getPostRevisions, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PostRevision
+	for rows.Next() {
+		var i PostRevision
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.PostID,
+			&i.Title,
+			&i.Description,
+			&i.Content,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -13,43 +13,88 @@ import (
 	"github.com/google/uuid"
 )
 
-const createPost = `-- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
-VALUES (
-  $1,
-  $2,
-  $3,
-  $4,
-  $5,
-  $6,
-  $7,
-  $8
-)
-RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id
+const adoptLegacyPost = `-- name: AdoptLegacyPost :exec
+UPDATE posts
+SET guid = $1
+WHERE posts.feed_id = $2
+  AND posts.guid = posts.url
+  AND posts.url = $3
+  AND NOT EXISTS (SELECT 1 FROM posts AS existing WHERE existing.feed_id = $2 AND existing.guid = $1)
 `
 
-type CreatePostParams struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       string
-	Url         string
-	Description string
-	PublishedAt time.Time
-	FeedID      uuid.UUID
+type AdoptLegacyPostParams struct {
+	Guid   string
+	FeedID uuid.UUID
+	Url    string
 }
 
-func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
-	row := q.db.QueryRowContext(ctx, createPost,
-		arg.ID,
-		arg.CreatedAt,
-		arg.UpdatedAt,
-		arg.Title,
-		arg.Url,
-		arg.Description,
-		arg.PublishedAt,
-		arg.FeedID,
+func (q *Queries) AdoptLegacyPost(ctx context.Context, arg AdoptLegacyPostParams) error {
+	_, err := q.db.ExecContext(ctx, adoptLegacyPost, arg.Guid, arg.FeedID, arg.Url)
+	return err
+}
+
+const getLatestPostByUrl = `-- name: GetLatestPostByUrl :one
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, guid, revised_at, content, author FROM posts
+WHERE url = $1
+ORDER BY updated_at DESC
+LIMIT 1
+`
+
+func (q *Queries) GetLatestPostByUrl(ctx context.Context, url string) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getLatestPostByUrl, url)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Guid,
+		&i.RevisedAt,
+		&i.Content,
+		&i.Author,
+	)
+	return i, err
+}
+
+const getPost = `-- name: GetPost :one
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, guid, revised_at, content, author FROM posts WHERE id = $1
+`
+
+func (q *Queries) GetPost(ctx context.Context, id uuid.UUID) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPost, id)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Guid,
+		&i.RevisedAt,
+		&i.Content,
+		&i.Author,
 	)
+	return i, err
+}
+
+const getPostByGuid = `-- name: GetPostByGuid :one
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, guid, revised_at, content, author FROM posts WHERE feed_id = $1 AND guid = $2
+`
+
+type GetPostByGuidParams struct {
+	FeedID uuid.UUID
+	Guid   string
+}
+
+func (q *Queries) GetPostByGuid(ctx context.Context, arg GetPostByGuidParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPostByGuid, arg.FeedID, arg.Guid)
 	var i Post
 	err := row.Scan(
 		&i.ID,
@@ -60,12 +105,16 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		&i.Description,
 		&i.PublishedAt,
 		&i.FeedID,
+		&i.Guid,
+		&i.RevisedAt,
+		&i.Content,
+		&i.Author,
 	)
 	return i, err
 }
 
 const getPostsByUser = `-- name: GetPostsByUser :many
-SELECT posts.id, posts.created_at, posts.updated_at, title, posts.url, description, published_at, feed_id, feeds.id, feeds.created_at, feeds.updated_at, name, feeds.url, user_id, last_fetched_at FROM posts
+SELECT posts.id, posts.created_at, posts.updated_at, title, posts.url, description, published_at, feed_id, guid, revised_at, content, author, feeds.id, feeds.created_at, feeds.updated_at, name, feeds.url, user_id, last_fetched_at, etag, last_modified, content_hash, consecutive_failures, last_error, last_error_at, last_success_at, next_retry_at, disabled_at, disabled_reason, next_fetch_at, ttl_seconds, skip_hours, skip_days, poll_interval_seconds, leased_by, lease_expires_at FROM posts
 INNER JOIN feeds ON posts.feed_id = feeds.id
 WHERE feeds.user_id = $1
 ORDER BY posts.published_at DESC
@@ -78,21 +127,42 @@ type GetPostsByUserParams struct {
 }
 
 type GetPostsByUserRow struct {
-	ID            uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	Title         string
-	Url           string
-	Description   string
-	PublishedAt   time.Time
-	FeedID        uuid.UUID
-	ID_2          uuid.UUID
-	CreatedAt_2   time.Time
-	UpdatedAt_2   time.Time
-	Name          string
-	Url_2         string
-	UserID        uuid.UUID
-	LastFetchedAt sql.NullTime
+	ID                  uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Title               string
+	Url                 string
+	Description         string
+	PublishedAt         time.Time
+	FeedID              uuid.UUID
+	Guid                string
+	RevisedAt           sql.NullTime
+	Content             string
+	Author              string
+	ID_2                uuid.UUID
+	CreatedAt_2         time.Time
+	UpdatedAt_2         time.Time
+	Name                string
+	Url_2               string
+	UserID              uuid.UUID
+	LastFetchedAt       sql.NullTime
+	Etag                string
+	LastModified        string
+	ContentHash         string
+	ConsecutiveFailures int32
+	LastError           string
+	LastErrorAt         sql.NullTime
+	LastSuccessAt       sql.NullTime
+	NextRetryAt         sql.NullTime
+	DisabledAt          sql.NullTime
+	DisabledReason      string
+	NextFetchAt         sql.NullTime
+	TtlSeconds          int32
+	SkipHours           int32
+	SkipDays            int32
+	PollIntervalSeconds int32
+	LeasedBy            string
+	LeaseExpiresAt      sql.NullTime
 }
 
 func (q *Queries) GetPostsByUser(ctx context.Context, arg GetPostsByUserParams) ([]GetPostsByUserRow, error) {
@@ -113,6 +183,10 @@ func (q *Queries) GetPostsByUser(ctx context.Context, arg GetPostsByUserParams)
 			&i.Description,
 			&i.PublishedAt,
 			&i.FeedID,
+			&i.Guid,
+			&i.RevisedAt,
+			&i.Content,
+			&i.Author,
 			&i.ID_2,
 			&i.CreatedAt_2,
 			&i.UpdatedAt_2,
@@ -120,6 +194,23 @@ func (q *Queries) GetPostsByUser(ctx context.Context, arg GetPostsByUserParams)
 			&i.Url_2,
 			&i.UserID,
 			&i.LastFetchedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.ContentHash,
+			&i.ConsecutiveFailures,
+			&i.LastError,
+			&i.LastErrorAt,
+			&i.LastSuccessAt,
+			&i.NextRetryAt,
+			&i.DisabledAt,
+			&i.DisabledReason,
+			&i.NextFetchAt,
+			&i.TtlSeconds,
+			&i.SkipHours,
+			&i.SkipDays,
+			&i.PollIntervalSeconds,
+			&i.LeasedBy,
+			&i.LeaseExpiresAt,
 		); err != nil {
 			return nil, err
 		}
@@ -133,3 +224,137 @@ func (q *Queries) GetPostsByUser(ctx context.Context, arg GetPostsByUserParams)
 	}
 	return items, nil
 }
+
+const getRecentPublishTimes = `-- name: GetRecentPublishTimes :many
+SELECT published_at FROM posts
+WHERE feed_id = $1
+ORDER BY published_at DESC
+LIMIT $2
+`
+
+type GetRecentPublishTimesParams struct {
+	FeedID uuid.UUID
+	Limit  int32
+}
+
+func (q *Queries) GetRecentPublishTimes(ctx context.Context, arg GetRecentPublishTimesParams) ([]time.Time, error) {
+	rows, err := q.db.QueryContext(ctx, getRecentPublishTimes, arg.FeedID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []time.Time
+	for rows.Next() {
+		var published_at time.Time
+		if err := rows.Scan(&published_at); err != nil {
+			return nil, err
+		}
+		items = append(items, published_at)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const movePosts = `-- name: MovePosts :exec
+UPDATE posts
+SET feed_id = $1
+WHERE posts.feed_id = $2
+  AND posts.guid NOT IN (SELECT existing.guid FROM posts AS existing WHERE existing.feed_id = $1)
+`
+
+type MovePostsParams struct {
+	ToFeedID   uuid.UUID
+	FromFeedID uuid.UUID
+}
+
+func (q *Queries) MovePosts(ctx context.Context, arg MovePostsParams) error {
+	_, err := q.db.ExecContext(ctx, movePosts, arg.ToFeedID, arg.FromFeedID)
+	return err
+}
+
+const upsertPost = `-- name: UpsertPost :one
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, guid, content, author)
+VALUES (
+  $1,
+  $2,
+  $3,
+  $4,
+  $5,
+  $6,
+  $7,
+  $8,
+  $9,
+  $10,
+  $11
+)
+ON CONFLICT (feed_id, guid) DO UPDATE
+SET title = EXCLUDED.title,
+    url = EXCLUDED.url,
+    description = EXCLUDED.description,
+    content = EXCLUDED.content,
+    author = EXCLUDED.author,
+    updated_at = EXCLUDED.updated_at,
+    -- content was not stored before, filling it in is not a revision, and
+    -- neither is a new author or url
+    revised_at = CASE
+      WHEN posts.title <> EXCLUDED.title OR posts.description <> EXCLUDED.description OR (posts.content <> '' AND posts.content <> EXCLUDED.content) THEN EXCLUDED.updated_at
+      ELSE posts.revised_at
+    END
+WHERE posts.title <> EXCLUDED.title
+   OR posts.description <> EXCLUDED.description
+   OR posts.content <> EXCLUDED.content
+   OR posts.author <> EXCLUDED.author
+   OR posts.url <> EXCLUDED.url
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, guid, revised_at, content, author
+`
+
+type UpsertPostParams struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description string
+	PublishedAt time.Time
+	FeedID      uuid.UUID
+	Guid        string
+	Content     string
+	Author      string
+}
+
+func (q *Queries) UpsertPost(ctx context.Context, arg UpsertPostParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, upsertPost,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Title,
+		arg.Url,
+		arg.Description,
+		arg.PublishedAt,
+		arg.FeedID,
+		arg.Guid,
+		arg.Content,
+		arg.Author,
+	)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Guid,
+		&i.RevisedAt,
+		&i.Content,
+		&i.Author,
+	)
+	return i, err
+}
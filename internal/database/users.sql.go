@@ -49,22 +49,39 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 }
 
 const feedsAndUsers = `-- name: FeedsAndUsers :many
-SELECT feeds.id, feeds.created_at, feeds.updated_at, feeds.name, url, user_id, last_fetched_at, users.id, users.created_at, users.updated_at, users.name FROM feeds
+SELECT feeds.id, feeds.created_at, feeds.updated_at, feeds.name, url, user_id, last_fetched_at, etag, last_modified, content_hash, consecutive_failures, last_error, last_error_at, last_success_at, next_retry_at, disabled_at, disabled_reason, next_fetch_at, ttl_seconds, skip_hours, skip_days, poll_interval_seconds, leased_by, lease_expires_at, users.id, users.created_at, users.updated_at, users.name FROM feeds
 INNER JOIN users ON users.id = feeds.user_id
 `
 
 type FeedsAndUsersRow struct {
-	ID            uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	Name          string
-	Url           string
-	UserID        uuid.UUID
-	LastFetchedAt sql.NullTime
-	ID_2          uuid.UUID
-	CreatedAt_2   time.Time
-	UpdatedAt_2   time.Time
-	Name_2        string
+	ID                  uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Name                string
+	Url                 string
+	UserID              uuid.UUID
+	LastFetchedAt       sql.NullTime
+	Etag                string
+	LastModified        string
+	ContentHash         string
+	ConsecutiveFailures int32
+	LastError           string
+	LastErrorAt         sql.NullTime
+	LastSuccessAt       sql.NullTime
+	NextRetryAt         sql.NullTime
+	DisabledAt          sql.NullTime
+	DisabledReason      string
+	NextFetchAt         sql.NullTime
+	TtlSeconds          int32
+	SkipHours           int32
+	SkipDays            int32
+	PollIntervalSeconds int32
+	LeasedBy            string
+	LeaseExpiresAt      sql.NullTime
+	ID_2                uuid.UUID
+	CreatedAt_2         time.Time
+	UpdatedAt_2         time.Time
+	Name_2              string
 }
 
 func (q *Queries) FeedsAndUsers(ctx context.Context) ([]FeedsAndUsersRow, error) {
@@ -84,6 +101,23 @@ func (q *Queries) FeedsAndUsers(ctx context.Context) ([]FeedsAndUsersRow, error)
 			&i.Url,
 			&i.UserID,
 			&i.LastFetchedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.ContentHash,
+			&i.ConsecutiveFailures,
+			&i.LastError,
+			&i.LastErrorAt,
+			&i.LastSuccessAt,
+			&i.NextRetryAt,
+			&i.DisabledAt,
+			&i.DisabledReason,
+			&i.NextFetchAt,
+			&i.TtlSeconds,
+			&i.SkipHours,
+			&i.SkipDays,
+			&i.PollIntervalSeconds,
+			&i.LeasedBy,
+			&i.LeaseExpiresAt,
 			&i.ID_2,
 			&i.CreatedAt_2,
 			&i.UpdatedAt_2,
@@ -7,13 +7,13 @@ import (
 	"github.com/samuelea/gator/internal/database"
 )
 
-func MiddlewareLoggedIn(handler func(s *config.State, cmd config.Command, user database.User) error) func(*config.State, config.Command) error {
-	return func(s *config.State, cmd config.Command) error {
-		user, err := s.DbQueries.GetUser(context.Background(), s.Config.CurrentUserName)
+func MiddlewareLoggedIn(handler func(ctx context.Context, s *config.State, cmd config.Command, user database.User) error) func(context.Context, *config.State, config.Command) error {
+	return func(ctx context.Context, s *config.State, cmd config.Command) error {
+		user, err := s.DbQueries.GetUser(ctx, s.Config.CurrentUserName)
 		if err != nil {
 			return err
 		}
 		
-		return handler(s, cmd, user)
+		return handler(ctx, s, cmd, user)
 	}
 }
\ No newline at end of file
@@ -4,8 +4,12 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"flag"
 	"fmt"
+	"log/slog"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/google/uuid"
@@ -18,7 +22,7 @@ import (
 )
 
 var cmds = config.Commands{
-	Handlers: map[string]func(*config.State, config.Command) error{
+	Handlers: map[string]func(context.Context, *config.State, config.Command) error{
 		"login": loginHandler,
 		"register": registerHandler,
 		"reset": resetHandler,
@@ -30,31 +34,77 @@ var cmds = config.Commands{
 		"following": middleware.MiddlewareLoggedIn(agg.FollowingHandler),
 		"unfollow": middleware.MiddlewareLoggedIn(agg.UnfollowHandler),
 		"browse": middleware.MiddlewareLoggedIn(agg.BrowseHandler),
+		"history": agg.HistoryHandler,
+		"download": middleware.MiddlewareLoggedIn(agg.DownloadHandler),
+		"notifications": middleware.MiddlewareLoggedIn(agg.NotificationsHandler),
+		"fetchlog": agg.FetchLogHandler,
+		"feed": middleware.MiddlewareLoggedIn(agg.FeedHandler),
 	},
 }
 
 func main() {
+	flags := flag.NewFlagSet("gator", flag.ContinueOnError)
+	logFormat := flags.String("log-format", "text", "format of the logs written to stderr: text or json")
+	quiet := flags.Bool("quiet", false, "only log warnings and errors")
+	verbose := flags.Bool("verbose", false, "also log debug messages, eg: every post saved by agg")
+
+	// global flags come before the command, eg: gator --log-format json agg 5m
+	err := flags.Parse(os.Args[1:])
+
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+
+	if err != nil {
+		os.Exit(2)
+	}
+
+	level, err := config.LogLevel(*quiet, *verbose)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(2)
+	}
+
+	logger, err := config.NewLogger(os.Stderr, *logFormat, level)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(2)
+	}
+
+	slog.SetDefault(logger)
+
 	gatorConfig, err := config.Read()
 	
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
+		slog.Error("failed to read config", "error", err)
+		os.Exit(1)
+	}
+
+	httpClient, err := config.NewHTTPClient(gatorConfig.HTTP)
+
+	if err != nil {
+		slog.Error("invalid http_client config", "error", err)
 		os.Exit(1)
 	}
 
 	db, err := sql.Open("postgres", gatorConfig.DBUrl)
 
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
+		slog.Error("failed to open database", "error", err)
 	}
 
 	dbQueries := database.New(db)
 
 	state := config.State{
 		Config: gatorConfig,
+		Db: db,
 		DbQueries: dbQueries,
+		HTTPClient: httpClient,
 	}
 
-	args := os.Args[1:]
+	args := flags.Args()
 
 	if len(args) == 0 {
 		fmt.Fprintf(os.Stderr, "No arguments provided\n")
@@ -66,15 +116,20 @@ func main() {
 		Args: args[1:],
 	}
 
-	err = cmds.Run(&state, command)
+	// interrupting or terminating cancels the context so long running
+	// commands like agg can stop cleanly
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err = cmds.Run(ctx, &state, command)
 
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
+		slog.Error("command failed", "command", command.Name, "user", gatorConfig.CurrentUserName, "error", err)
 		os.Exit(1)
 	}
 }
 
-func loginHandler (state *config.State, command config.Command) error {
+func loginHandler(ctx context.Context, state *config.State, command config.Command) error {
 	if len(command.Args) == 0 {
 		return errors.New("no username entered") 
 	}
@@ -84,7 +139,7 @@ func loginHandler (state *config.State, command config.Command) error {
 
 	username := command.Args[0]
 
-	err := loginUser(state, username)
+	err := loginUser(ctx, state, username)
 
 	if err != nil {
 		return err
@@ -93,8 +148,8 @@ func loginHandler (state *config.State, command config.Command) error {
 	return nil
 }
 
-func loginUser(state *config.State, username string) error {
-	_, err := state.DbQueries.GetUser(context.Background(), username)
+func loginUser(ctx context.Context, state *config.State, username string) error {
+	_, err := state.DbQueries.GetUser(ctx, username)
 
 	if err != nil {
 		return err
@@ -111,12 +166,12 @@ func loginUser(state *config.State, username string) error {
 	return nil
 } 
 
-func registerHandler(state *config.State, command config.Command) error {
+func registerHandler(ctx context.Context, state *config.State, command config.Command) error {
 	if len(command.Args) == 0 {
 		return fmt.Errorf("no username provided")
 	}
 
-	_, err := state.DbQueries.CreateUser(context.Background(), database.CreateUserParams{
+	_, err := state.DbQueries.CreateUser(ctx, database.CreateUserParams{
 		ID: uuid.New(),
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
@@ -127,7 +182,7 @@ func registerHandler(state *config.State, command config.Command) error {
 		return fmt.Errorf("failed to create new user %s. user already exists", command.Args[0])
 	}
 
-	err = loginUser(state, command.Args[0])
+	err = loginUser(ctx, state, command.Args[0])
 
 	if err != nil {
 		return err
@@ -136,8 +191,8 @@ func registerHandler(state *config.State, command config.Command) error {
 	return nil
 }
 
-func resetHandler(state *config.State, command config.Command) error {
-	err := state.DbQueries.Reset(context.Background())
+func resetHandler(ctx context.Context, state *config.State, command config.Command) error {
+	err := state.DbQueries.Reset(ctx)
 
 	if err != nil {
 		return err
@@ -146,8 +201,8 @@ func resetHandler(state *config.State, command config.Command) error {
 	return nil
 }
 
-func listHandler(state *config.State, command config.Command) error {
-	users, err := state.DbQueries.GetUsers(context.Background())
+func listHandler(ctx context.Context, state *config.State, command config.Command) error {
+	users, err := state.DbQueries.GetUsers(ctx)
 
 	if err != nil {
 		return err
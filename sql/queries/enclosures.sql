@@ -0,0 +1,46 @@
+-- name: UpsertEnclosure :exec
+INSERT INTO enclosures (id, created_at, updated_at, post_id, url, length, mime_type)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (post_id, url) DO UPDATE
+SET length = EXCLUDED.length,
+    mime_type = EXCLUDED.mime_type,
+    updated_at = EXCLUDED.updated_at
+WHERE enclosures.length <> EXCLUDED.length
+   OR enclosures.mime_type <> EXCLUDED.mime_type;
+
+-- name: GetEnclosuresForPost :many
+SELECT * FROM enclosures
+WHERE post_id = $1
+ORDER BY created_at ASC;
+
+-- name: GetEnclosuresForUser :many
+SELECT enclosures.*,
+       posts.feed_id,
+       posts.published_at,
+       feeds.name AS feed_name,
+       feed_follows.download_keep_last,
+       enclosure_downloads.downloaded_at,
+       COALESCE(enclosure_downloads.file_path, '')::TEXT AS file_path
+FROM enclosures
+INNER JOIN posts ON posts.id = enclosures.post_id
+INNER JOIN feeds ON feeds.id = posts.feed_id
+INNER JOIN feed_follows ON feed_follows.feed_id = feeds.id
+LEFT JOIN enclosure_downloads ON enclosure_downloads.enclosure_id = enclosures.id
+  AND enclosure_downloads.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+ORDER BY posts.feed_id, posts.published_at DESC;
+
+-- name: MarkEnclosureDownloaded :exec
+INSERT INTO enclosure_downloads (user_id, enclosure_id, file_path, downloaded_at)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (user_id, enclosure_id) DO UPDATE
+SET file_path = EXCLUDED.file_path,
+    downloaded_at = EXCLUDED.downloaded_at;
+
+-- name: ClearEnclosureDownload :exec
+DELETE FROM enclosure_downloads
+WHERE user_id = $1 AND enclosure_id = $2;
+
+-- name: CountOtherDownloadsOfFile :one
+SELECT COUNT(*) FROM enclosure_downloads
+WHERE file_path = $1 AND user_id <> $2;
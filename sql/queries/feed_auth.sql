@@ -0,0 +1,14 @@
+-- name: UpsertFeedAuth :exec
+INSERT INTO feed_auth (feed_id, created_at, updated_at, credentials)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (feed_id) DO UPDATE
+SET credentials = EXCLUDED.credentials,
+    updated_at = EXCLUDED.updated_at;
+
+-- name: GetFeedAuth :one
+SELECT * FROM feed_auth
+WHERE feed_id = $1;
+
+-- name: DeleteFeedAuth :exec
+DELETE FROM feed_auth
+WHERE feed_id = $1;
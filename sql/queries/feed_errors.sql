@@ -0,0 +1,19 @@
+-- name: CreateFeedError :exec
+INSERT INTO feed_errors (id, created_at, feed_id, error)
+VALUES ($1, $2, $3, $4);
+
+-- name: GetFeedErrors :many
+SELECT * FROM feed_errors
+WHERE feed_id = $1
+ORDER BY created_at DESC
+LIMIT $2;
+
+-- name: TrimFeedErrors :exec
+DELETE FROM feed_errors
+WHERE feed_errors.feed_id = $1
+  AND feed_errors.id NOT IN (
+    SELECT recent.id FROM feed_errors AS recent
+    WHERE recent.feed_id = $1
+    ORDER BY recent.created_at DESC
+    LIMIT $2
+  );
@@ -25,4 +25,16 @@ SELECT feed_follows.*,
        feeds.url as feed_url
 FROM feed_follows
 INNER JOIN feeds ON feeds.id = feed_follows.feed_id
-WHERE feed_follows.user_id = $1;
\ No newline at end of file
+WHERE feed_follows.user_id = $1;
+
+-- name: MoveFeedFollows :exec
+INSERT INTO feed_follows (id, created_at, updated_at, feed_id, user_id, download_keep_last)
+SELECT uuid_generate_v4(), sqlc.arg(created_at), sqlc.arg(created_at), sqlc.arg(to_feed_id), feed_follows.user_id, feed_follows.download_keep_last
+FROM feed_follows
+WHERE feed_follows.feed_id = sqlc.arg(from_feed_id)
+ON CONFLICT (feed_id, user_id) DO NOTHING;
+
+-- name: SetFeedFollowKeepLast :execrows
+UPDATE feed_follows
+SET download_keep_last = $3, updated_at = $4
+WHERE feed_id = $1 AND user_id = $2;
@@ -11,9 +11,74 @@ SELECT * FROM feeds WHERE url = $1;
 
 -- name: MarkFeedFetched :exec
 UPDATE feeds
-SET last_fetched_at = $2, updated_at = $2
+SET last_fetched_at = $2,
+    updated_at = $2,
+    last_success_at = $2,
+    consecutive_failures = 0,
+    next_retry_at = NULL
 WHERE id = $1;
 
--- name: GetNextFeedToFetch :one
-SELECT * FROM feeds 
-ORDER BY last_fetched_at DESC NULLS FIRST;
+-- name: RecordFeedFailure :exec
+UPDATE feeds
+SET last_fetched_at = $2,
+    updated_at = $2,
+    last_error_at = $2,
+    consecutive_failures = consecutive_failures + 1,
+    last_error = $3,
+    next_retry_at = $4
+WHERE id = $1;
+
+-- name: GetFailingFeeds :many
+SELECT * FROM feeds
+WHERE consecutive_failures > 0
+ORDER BY consecutive_failures DESC, name;
+
+-- name: ClaimDueFeeds :many
+UPDATE feeds
+SET leased_by = sqlc.arg(worker_id), lease_expires_at = sqlc.arg(lease_expires_at)
+WHERE feeds.id IN (
+  SELECT due.id FROM feeds AS due
+  WHERE (due.next_fetch_at IS NULL OR due.next_fetch_at <= sqlc.arg(now))
+    AND (due.next_retry_at IS NULL OR due.next_retry_at <= sqlc.arg(now))
+    AND (due.lease_expires_at IS NULL OR due.lease_expires_at <= sqlc.arg(now))
+    AND due.disabled_at IS NULL
+  ORDER BY due.last_fetched_at ASC NULLS FIRST
+  LIMIT sqlc.arg(batch_size)
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: ReleaseFeedLease :exec
+UPDATE feeds
+SET leased_by = '', lease_expires_at = NULL
+WHERE id = $1 AND leased_by = $2;
+
+-- name: UpdateFeedCacheValidators :exec
+UPDATE feeds
+SET etag = $2, last_modified = $3, content_hash = $4
+WHERE id = $1;
+
+-- name: UpdateFeedUrl :exec
+UPDATE feeds
+SET url = $2, updated_at = $3
+WHERE id = $1;
+
+-- name: DisableFeed :exec
+UPDATE feeds
+SET disabled_at = $2, updated_at = $2, disabled_reason = $3
+WHERE id = $1;
+
+-- name: DeleteFeed :exec
+DELETE FROM feeds WHERE id = $1;
+
+-- name: UpdateFeedSchedule :exec
+UPDATE feeds
+SET next_fetch_at = $2, ttl_seconds = $3, skip_hours = $4, skip_days = $5, poll_interval_seconds = $6
+WHERE id = $1;
+
+-- name: CountDueFeeds :one
+SELECT COUNT(*) FROM feeds
+WHERE (next_fetch_at IS NULL OR next_fetch_at <= sqlc.arg(now))
+  AND (next_retry_at IS NULL OR next_retry_at <= sqlc.arg(now))
+  AND (lease_expires_at IS NULL OR lease_expires_at <= sqlc.arg(now))
+  AND disabled_at IS NULL;
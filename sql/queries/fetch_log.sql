@@ -0,0 +1,22 @@
+-- name: CreateFetchLog :exec
+INSERT INTO fetch_log (id, feed_id, started_at, duration_ms, http_status, bytes, items_seen, items_new, items_dropped, error, body)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11);
+
+-- name: GetFetchLog :many
+SELECT fetch_log.*, feeds.name AS feed_name, feeds.url AS feed_url
+FROM fetch_log
+INNER JOIN feeds ON fetch_log.feed_id = feeds.id
+WHERE (sqlc.narg(feed_id)::uuid IS NULL OR fetch_log.feed_id = sqlc.narg(feed_id))
+  AND (NOT sqlc.arg(failed_only)::boolean OR fetch_log.error <> '')
+ORDER BY fetch_log.started_at DESC
+LIMIT sqlc.arg(row_limit);
+
+-- name: TrimFetchLog :exec
+DELETE FROM fetch_log
+WHERE fetch_log.feed_id = $1
+  AND fetch_log.id NOT IN (
+    SELECT recent.id FROM fetch_log AS recent
+    WHERE recent.feed_id = $1
+    ORDER BY recent.started_at DESC
+    LIMIT $2
+  );
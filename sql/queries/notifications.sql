@@ -0,0 +1,15 @@
+-- name: NotifyFeedFollowers :exec
+INSERT INTO notifications (created_at, user_id, message)
+SELECT $2, feed_follows.user_id, $3
+FROM feed_follows
+WHERE feed_follows.feed_id = $1;
+
+-- name: GetUnreadNotifications :many
+SELECT * FROM notifications
+WHERE user_id = $1 AND read_at IS NULL
+ORDER BY created_at ASC;
+
+-- name: MarkNotificationsRead :exec
+UPDATE notifications
+SET read_at = $2
+WHERE user_id = $1 AND read_at IS NULL;
@@ -0,0 +1,12 @@
+-- name: CreatePostRevision :execrows
+INSERT INTO post_revisions (id, created_at, post_id, title, description, content)
+SELECT $1, $2, posts.id, posts.title, posts.description, posts.content
+FROM posts
+WHERE posts.feed_id = $3
+  AND posts.guid = $4
+  AND (posts.title <> $5 OR posts.description <> $6 OR (posts.content <> $7 AND posts.content <> ''));
+
+-- name: GetPostRevisions :many
+SELECT * FROM post_revisions
+WHERE post_id = $1
+ORDER BY created_at ASC;
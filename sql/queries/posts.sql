@@ -1,5 +1,5 @@
--- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
+-- name: UpsertPost :one
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, guid, content, author)
 VALUES (
   $1,
   $2,
@@ -8,13 +8,68 @@ VALUES (
   $5,
   $6,
   $7,
-  $8
+  $8,
+  $9,
+  $10,
+  $11
 )
+ON CONFLICT (feed_id, guid) DO UPDATE
+SET title = EXCLUDED.title,
+    url = EXCLUDED.url,
+    description = EXCLUDED.description,
+    content = EXCLUDED.content,
+    author = EXCLUDED.author,
+    updated_at = EXCLUDED.updated_at,
+    -- content was not stored before, filling it in is not a revision, and
+    -- neither is a new author or url
+    revised_at = CASE
+      WHEN posts.title <> EXCLUDED.title OR posts.description <> EXCLUDED.description OR (posts.content <> '' AND posts.content <> EXCLUDED.content) THEN EXCLUDED.updated_at
+      ELSE posts.revised_at
+    END
+WHERE posts.title <> EXCLUDED.title
+   OR posts.description <> EXCLUDED.description
+   OR posts.content <> EXCLUDED.content
+   OR posts.author <> EXCLUDED.author
+   OR posts.url <> EXCLUDED.url
 RETURNING *;
 
+-- name: AdoptLegacyPost :exec
+UPDATE posts
+SET guid = sqlc.arg(guid)
+WHERE posts.feed_id = sqlc.arg(feed_id)
+  AND posts.guid = posts.url
+  AND posts.url = sqlc.arg(url)
+  AND NOT EXISTS (SELECT 1 FROM posts AS existing WHERE existing.feed_id = sqlc.arg(feed_id) AND existing.guid = sqlc.arg(guid));
+
 -- name: GetPostsByUser :many
 SELECT * FROM posts
 INNER JOIN feeds ON posts.feed_id = feeds.id
 WHERE feeds.user_id = $1
 ORDER BY posts.published_at DESC
-LIMIT $2;
\ No newline at end of file
+LIMIT $2;
+
+-- name: GetPost :one
+SELECT * FROM posts WHERE id = $1;
+
+-- name: GetPostByGuid :one
+SELECT * FROM posts WHERE feed_id = $1 AND guid = $2;
+
+-- name: GetLatestPostByUrl :one
+SELECT * FROM posts
+WHERE url = $1
+ORDER BY updated_at DESC
+LIMIT 1;
+
+
+-- name: MovePosts :exec
+UPDATE posts
+SET feed_id = sqlc.arg(to_feed_id)
+WHERE posts.feed_id = sqlc.arg(from_feed_id)
+  AND posts.guid NOT IN (SELECT existing.guid FROM posts AS existing WHERE existing.feed_id = sqlc.arg(to_feed_id));
+
+
+-- name: GetRecentPublishTimes :many
+SELECT published_at FROM posts
+WHERE feed_id = $1
+ORDER BY published_at DESC
+LIMIT $2;
@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD etag TEXT NOT NULL DEFAULT '',
+ADD last_modified TEXT NOT NULL DEFAULT '',
+ADD content_hash TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE feeds
+DROP etag,
+DROP last_modified,
+DROP content_hash;
@@ -0,0 +1,19 @@
+-- +goose Up
+ALTER TABLE posts
+ADD guid TEXT NOT NULL DEFAULT '',
+ADD revised_at TIMESTAMP;
+
+-- the real guid is not known yet. savePost adopts these rows by url the next
+-- time their item is fetched so it is not saved again under its guid
+UPDATE posts SET guid = url;
+
+ALTER TABLE posts
+DROP CONSTRAINT posts_url_key,
+ADD CONSTRAINT posts_feed_id_guid_key UNIQUE (feed_id, guid);
+
+-- +goose Down
+ALTER TABLE posts
+DROP CONSTRAINT posts_feed_id_guid_key,
+ADD CONSTRAINT posts_url_key UNIQUE (url),
+DROP guid,
+DROP revised_at;
@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE enclosures (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    post_id UUID NOT NULL,
+    url TEXT NOT NULL,
+    length BIGINT NOT NULL,
+    mime_type TEXT NOT NULL,
+    downloaded_at TIMESTAMP,
+    file_path TEXT NOT NULL DEFAULT '',
+    CONSTRAINT fk_enclosures_posts FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
+    UNIQUE (post_id, url)
+);
+
+-- +goose Down
+DROP TABLE enclosures;
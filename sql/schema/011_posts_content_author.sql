@@ -0,0 +1,15 @@
+-- +goose Up
+ALTER TABLE posts
+ADD content TEXT NOT NULL DEFAULT '',
+ADD author TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE post_revisions
+ADD content TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE post_revisions
+DROP content;
+
+ALTER TABLE posts
+DROP content,
+DROP author;
@@ -0,0 +1,25 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD consecutive_failures INTEGER NOT NULL DEFAULT 0,
+ADD last_error TEXT NOT NULL DEFAULT '',
+ADD last_error_at TIMESTAMP,
+ADD last_success_at TIMESTAMP,
+ADD next_retry_at TIMESTAMP;
+
+CREATE TABLE feed_errors (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    created_at TIMESTAMP NOT NULL,
+    feed_id UUID NOT NULL,
+    error TEXT NOT NULL,
+    CONSTRAINT fk_feed_errors_feeds FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE feed_errors;
+
+ALTER TABLE feeds
+DROP consecutive_failures,
+DROP last_error,
+DROP last_error_at,
+DROP last_success_at,
+DROP next_retry_at;
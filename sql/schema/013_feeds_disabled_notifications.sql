@@ -0,0 +1,20 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD disabled_at TIMESTAMP,
+ADD disabled_reason TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE notifications (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL,
+    message TEXT NOT NULL,
+    read_at TIMESTAMP,
+    CONSTRAINT fk_notifications_users FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE notifications;
+
+ALTER TABLE feeds
+DROP disabled_at,
+DROP disabled_reason;
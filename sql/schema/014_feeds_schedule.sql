@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD next_fetch_at TIMESTAMP,
+ADD ttl_seconds INTEGER NOT NULL DEFAULT 0,
+ADD skip_hours INTEGER NOT NULL DEFAULT 0,
+ADD skip_days INTEGER NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP next_fetch_at,
+DROP ttl_seconds,
+DROP skip_hours,
+DROP skip_days;
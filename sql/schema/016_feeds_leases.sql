@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD leased_by TEXT NOT NULL DEFAULT '',
+ADD lease_expires_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP leased_by,
+DROP lease_expires_at;
@@ -0,0 +1,19 @@
+-- +goose Up
+CREATE TABLE fetch_log (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    feed_id UUID NOT NULL,
+    started_at TIMESTAMP NOT NULL,
+    duration_ms INTEGER NOT NULL,
+    http_status INTEGER NOT NULL DEFAULT 0,
+    bytes BIGINT NOT NULL DEFAULT 0,
+    items_seen INTEGER NOT NULL DEFAULT 0,
+    items_new INTEGER NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    body BYTEA,
+    CONSTRAINT fk_fetch_log_feeds FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+);
+
+CREATE INDEX fetch_log_feed_id_started_at ON fetch_log (feed_id, started_at DESC);
+
+-- +goose Down
+DROP TABLE fetch_log;
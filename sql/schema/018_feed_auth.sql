@@ -0,0 +1,11 @@
+-- +goose Up
+CREATE TABLE feed_auth (
+    feed_id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    credentials BYTEA NOT NULL,
+    CONSTRAINT fk_feed_auth_feeds FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE feed_auth;
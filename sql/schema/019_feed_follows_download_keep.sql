@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE feed_follows
+ADD download_keep_last INTEGER;
+
+-- +goose Down
+ALTER TABLE feed_follows
+DROP download_keep_last;
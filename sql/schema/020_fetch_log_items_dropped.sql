@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE fetch_log
+ADD items_dropped INTEGER NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE fetch_log
+DROP items_dropped;
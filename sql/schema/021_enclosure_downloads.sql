@@ -0,0 +1,37 @@
+-- +goose Up
+CREATE TABLE enclosure_downloads (
+    user_id UUID NOT NULL,
+    enclosure_id UUID NOT NULL,
+    file_path TEXT NOT NULL,
+    downloaded_at TIMESTAMP NOT NULL,
+    CONSTRAINT fk_enclosure_downloads_users FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_enclosure_downloads_enclosures FOREIGN KEY (enclosure_id) REFERENCES enclosures(id) ON DELETE CASCADE,
+    PRIMARY KEY (user_id, enclosure_id)
+);
+
+CREATE INDEX enclosure_downloads_file_path ON enclosure_downloads (file_path);
+
+-- which follower downloaded an enclosure was not stored, so every follower
+-- shares the file until the last of them no longer keeps it
+INSERT INTO enclosure_downloads (user_id, enclosure_id, file_path, downloaded_at)
+SELECT feed_follows.user_id, enclosures.id, enclosures.file_path, enclosures.downloaded_at
+FROM enclosures
+INNER JOIN posts ON posts.id = enclosures.post_id
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE enclosures.downloaded_at IS NOT NULL;
+
+ALTER TABLE enclosures
+DROP downloaded_at,
+DROP file_path;
+
+-- +goose Down
+ALTER TABLE enclosures
+ADD downloaded_at TIMESTAMP,
+ADD file_path TEXT NOT NULL DEFAULT '';
+
+UPDATE enclosures
+SET downloaded_at = enclosure_downloads.downloaded_at, file_path = enclosure_downloads.file_path
+FROM enclosure_downloads
+WHERE enclosure_downloads.enclosure_id = enclosures.id;
+
+DROP TABLE enclosure_downloads;